@@ -0,0 +1,73 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// adminAuth is a middleware that only allows requests with the configured Admin Token as
+// bearer token. Admin endpoints are not available if no token is configured.
+func (s *Server) adminAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := s.cfg().Admin.Token
+		if token == "" {
+			s.sendError(w, r, errCodeNotFound, "admin endpoints are disabled")
+			return
+		}
+		auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(auth), []byte(token)) != 1 {
+			s.reqLog(r).Info("Unauthorized admin request", "path", r.URL.Path, "addr", r.RemoteAddr)
+			s.sendError(w, r, errCodeUnauthenticated, "missing or invalid admin token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleAdminReload reloads the config, see reloadConfig
+func (s *Server) handleAdminReload(w http.ResponseWriter, r *http.Request) {
+	err := s.reloadConfig()
+
+	s.reload.mu.Lock()
+	result := struct {
+		Data struct {
+			Status  string   `json:"status"`
+			Error   string   `json:"error,omitempty"`
+			Added   []string `json:"added"`
+			Removed []string `json:"removed"`
+		} `json:"data"`
+	}{}
+	result.Data.Status = "ok"
+	result.Data.Added = s.reload.added
+	result.Data.Removed = s.reload.removed
+	s.reload.mu.Unlock()
+
+	code := http.StatusOK
+	if err != nil {
+		code = http.StatusBadRequest
+		result.Data.Status = "rejected"
+		result.Data.Error = err.Error()
+	}
+	output, _ := json.Marshal(result)
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(code)
+	w.Write(output)
+}
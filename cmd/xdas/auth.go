@@ -0,0 +1,73 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"xdas/internal/auth"
+	"xdas/internal/logger/weblog"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// authenticate is a middleware that identifies the principal of the request and adds it to the
+// request context, if Auth is enabled. Requests without valid credentials are rejected with 401.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a := &s.cfg().Auth
+		if !a.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+		p, err := a.Authenticate(r)
+		if err != nil {
+			if !errors.Is(err, auth.ErrUnauthenticated) {
+				s.sendRequestBodyReadErr(w, r, err)
+				return
+			}
+			s.reqLog(r).Info("Unauthenticated request", "path", r.URL.Path, "addr", r.RemoteAddr, "err", err)
+			s.sendError(w, r, errCodeUnauthenticated, "missing or invalid credentials")
+			return
+		}
+		weblog.AddAttrs(r.Context(), "principal", p.Name)
+		next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), p)))
+	})
+}
+
+// authorize is a middleware that checks the principal has perm on the keyspace of the request,
+// or rejects it with 403. It must be used after authenticate.
+func (s *Server) authorize(perm auth.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keyspace := chi.URLParam(r, "keyspace")
+			if !s.allowed(r, keyspace, perm) {
+				s.reqLog(r).Info("Forbidden request", "principal", auth.FromContext(r.Context()), "keyspace", keyspace,
+					"perm", perm, "path", r.URL.Path)
+				s.sendError(w, r, errCodeForbidden, "no "+string(perm)+" permission on keyspace")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// allowed reports whether the principal of the request has perm on keyspace, always true if Auth
+// is not enabled
+func (s *Server) allowed(r *http.Request, keyspace string, perm auth.Permission) bool {
+	return !s.cfg().Auth.Enabled || auth.FromContext(r.Context()).Allowed(keyspace, perm)
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"xdas/internal/auth"
+)
+
+func TestAuthenticateAuthorize(t *testing.T) {
+	const conf = `{
+		"Auth": {
+			"Enabled": %s,
+			"Principals": {
+				"reader": {"APIKeys": ["readerKey"], "Permissions": {"*": ["read"]}},
+				"writer": {"APIKeys": ["writerKey"], "Permissions": {"gs": ["read", "write"]}},
+				"owner": {"APIKeys": ["ownerKey"], "Permissions": {"*": ["read", "write", "delete"]}}
+			}
+		},
+		"Keyspaces": {"gs": {"ttl": "1h"}, "other": {"ttl": "1h"}}
+	}`
+	servers := make(map[bool]*Server)
+	for _, enabled := range []bool{false, true} {
+		servers[enabled], _ = newTestServer(t, fmt.Sprintf(conf, strconv.FormatBool(enabled)))
+		// the records read by the tests
+		for _, keyspace := range []string{"gs", "other"} {
+			w := serve(servers[enabled], http.MethodPut, "/v2/"+keyspace+"/A", "x", map[string]string{auth.HeaderAPIKey: "ownerKey"})
+			if w.Code != http.StatusOK {
+				t.Fatalf("PUT %s got: %d, want: %d", keyspace, w.Code, http.StatusOK)
+			}
+		}
+	}
+
+	tests := []struct {
+		name       string
+		enabled    bool
+		method     string
+		target     string
+		apiKey     string
+		wantStatus int
+		wantCode   string // error code
+	}{
+		{"disabled without credentials", false, http.MethodPut, "/v2/gs/A", "", http.StatusOK, ""},
+		{"disabled with invalid key", false, http.MethodGet, "/v2/other/A", "invalid", http.StatusOK, ""},
+		{"disabled delete", false, http.MethodDelete, "/v2/other/A", "", http.StatusOK, ""},
+		{"no credentials", true, http.MethodGet, "/v2/gs/A", "", http.StatusUnauthorized, errCodeUnauthenticated},
+		{"invalid key", true, http.MethodGet, "/v2/gs/A", "invalid", http.StatusUnauthorized, errCodeUnauthenticated},
+		{"reader read", true, http.MethodGet, "/v2/other/A", "readerKey", http.StatusOK, ""},
+		{"reader write", true, http.MethodPut, "/v2/gs/A", "readerKey", http.StatusForbidden, errCodeForbidden},
+		{"writer write", true, http.MethodPut, "/v2/gs/A", "writerKey", http.StatusOK, ""},
+		{"writer read", true, http.MethodGet, "/v2/gs/A", "writerKey", http.StatusOK, ""},
+		{"writer delete", true, http.MethodDelete, "/v2/gs/A", "writerKey", http.StatusForbidden, errCodeForbidden},
+		{"writer other keyspace", true, http.MethodGet, "/v2/other/A", "writerKey", http.StatusForbidden, errCodeForbidden},
+		{"unknown keyspace", true, http.MethodGet, "/v2/unknown/A", "", http.StatusUnauthorized, errCodeUnauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := map[string]string{"Accept": "application/json"}
+			if tt.apiKey != "" {
+				header[auth.HeaderAPIKey] = tt.apiKey
+			}
+			w := serve(servers[tt.enabled], tt.method, tt.target, "x", header)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status got: %d, want: %d, body: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			var result struct{ Error apiError }
+			if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil || result.Error.Code != tt.wantCode {
+				t.Errorf("error got: %s, want code: %s", w.Body, tt.wantCode)
+			}
+		})
+	}
+}
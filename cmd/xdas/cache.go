@@ -0,0 +1,86 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"xdas/internal/cache"
+)
+
+// newCache subscribes to the invalidations of other pods once a keyspace has Cache.Invalidate. The
+// subscription is kept until the server shuts down.
+func (s *Server) newCache(ksConfs map[string]*KeyspaceConfig) {
+	if s.redis == nil {
+		return
+	}
+	for _, ksConf := range ksConfs {
+		if ksConf.Cache.Enabled && ksConf.Cache.Invalidate {
+			s.invalidations.Do(s.subscribeInvalidations)
+			return
+		}
+	}
+}
+
+func (s *Server) subscribeInvalidations() {
+	s.log.Info("Cache invalidation is starting...")
+	sub := s.redis.Subscribe(context.Background(), cache.Channel)
+	go func() {
+		defer sub.Close()
+		ch := sub.Channel()
+		for {
+			select {
+			case <-s.closing:
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				s.dropCached(msg.Payload)
+			}
+		}
+	}()
+}
+
+// dropCached drops key, in the form of redisKey, from the cache of its keyspace
+func (s *Server) dropCached(key string) {
+	keyspace, _, ok := strings.Cut(key, ":")
+	if !ok {
+		return
+	}
+	if ksConf, ok := s.cfg().Keyspaces[keyspace]; ok {
+		ksConf.Cache.Delete(key)
+	}
+}
+
+// invalidate drops the key of id from the cache of keyspace after a write, and publishes it to the
+// other pods if the keyspace has Cache.Invalidate
+func (s *Server) invalidate(ctx context.Context, r *http.Request, ksConf *KeyspaceConfig, keyspace, id string) {
+	if !ksConf.Cache.Enabled {
+		return
+	}
+	key := redisKey(keyspace, id)
+	ksConf.Cache.Delete(key)
+	if !ksConf.Cache.Invalidate || s.redis == nil {
+		return
+	}
+	if err := s.redis.Publish(ctx, cache.Channel, key).Err(); err != nil {
+		s.reqLog(r).Error("Redis invalidate error", "keyspace", keyspace, "id", id, "err", err)
+		s.metrics.redisInvalErr.Inc()
+	}
+}
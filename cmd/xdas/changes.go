@@ -0,0 +1,238 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"xdas/internal/conversion"
+	"xdas/internal/magicbyte"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultChangesMaxLen   = 100000
+	defaultChangesCount    = 100
+	maxChangesCount        = 1000
+	defaultChangesWait     = 5 * time.Second
+	maxChangesWait         = 30 * time.Second
+	defaultChangesMaxPolls = 100
+)
+
+// ChangesConfig holds config for publishing changes of a keyspace to a Redis Stream
+type ChangesConfig struct {
+	Enabled     bool
+	MaxLen      int64 // approximate max length of the stream, default 100000
+	IncludeBody bool  // store the record in the stream
+}
+
+// change is a single entry of the changes stream
+type change struct {
+	StreamID        string `json:"streamId,omitempty"`
+	Keyspace        string `json:"keyspace"`
+	ID              string `json:"id"`
+	Op              string `json:"op"`
+	MagicByte       int    `json:"magicByte"`
+	TTL             int64  `json:"ttl"`       // seconds
+	Timestamp       int64  `json:"timestamp"` // unix ms
+	ContentType     string `json:"contentType,omitempty"`
+	ContentEncoding string `json:"contentEncoding,omitempty"`
+	Payload         []byte `json:"payload,omitempty"`
+}
+
+// redisKeyChanges construct the key of the changes stream for keyspace
+func redisKeyChanges(keyspace string) string { return "changes:{" + keyspace + "}" }
+
+// onChange is called after a record is successfully written or deleted. Its Redis calls are not
+// canceled if the client goes away, as the change is already made.
+func (s *Server) onChange(r *http.Request, ksConf *KeyspaceConfig, keyspace, op, id string, ttl time.Duration,
+	magicByte magicbyte.MagicByte, data []byte) {
+	ctx, cancel := withRedisTimeout(context.WithoutCancel(r.Context()), ksConf)
+	defer cancel()
+	s.invalidate(ctx, r, ksConf, keyspace, id)
+	s.publishChange(ctx, r, ksConf, keyspace, op, id, ttl, magicByte, data)
+	s.notifyChange(ctx, r, ksConf, keyspace, op, id, ttl, magicByte, data)
+}
+
+// publishChange adds the change to the changes stream of the keyspace. data is stored as is,
+// in Store format.
+func (s *Server) publishChange(ctx context.Context, r *http.Request, ksConf *KeyspaceConfig, keyspace, op, id string, ttl time.Duration,
+	magicByte magicbyte.MagicByte, data []byte) {
+	if !ksConf.Changes.Enabled {
+		return
+	}
+	values := map[string]interface{}{
+		"id":  id,
+		"op":  op,
+		"mb":  int(magicByte.Get()),
+		"ttl": int64(ttl / time.Second),
+		"ts":  time.Now().UnixMilli(),
+	}
+	if ksConf.Changes.IncludeBody && len(data) > 0 {
+		values["payload"] = data
+	}
+	err := s.redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: redisKeyChanges(keyspace),
+		MaxLen: ksConf.Changes.MaxLen,
+		Approx: true,
+		Values: values,
+	}).Err()
+	if err != nil {
+		s.reqLog(r).Error("Redis changes error", "keyspace", keyspace, "id", id, "err", err)
+		s.metrics.redisChangesErr.Inc()
+	}
+}
+
+// handleFuncXdasChanges long-polls the changes stream of a keyspace for entries after the query
+// parameter since, a stream ID. If since is not set or "$", only changes from now on are returned.
+func (s *Server) handleFuncXdasChanges(w http.ResponseWriter, r *http.Request) {
+	keyspace := chi.URLParam(r, "keyspace")
+	ksConf, ok := s.cfg().Keyspaces[keyspace]
+	if !ok || !ksConf.Changes.Enabled {
+		s.sendError(w, r, errCodeNotFound, "changes are not enabled for keyspace")
+		return
+	}
+	key := redisKeyChanges(keyspace)
+
+	query := r.URL.Query()
+	since := query.Get("since")
+	if since != "" && since != "$" && !isStreamID(since) {
+		s.sendError(w, r, errCodeBadRequest, "since must be a stream ID, <ms>[-<seq>]")
+		return
+	}
+	if since == "" || since == "$" {
+		since = "0-0"
+		ctx, cancel := redisContext(r, ksConf)
+		last, err := s.redis.XRevRangeN(ctx, key, "+", "-", 1).Result()
+		cancel()
+		if err != nil {
+			s.sendRedisReadErr(w, r, err)
+			return
+		}
+		if len(last) > 0 {
+			since = last[0].ID
+		}
+	}
+	count, _ := strconv.ParseInt(query.Get("count"), 10, 0)
+	if count < 1 {
+		count = defaultChangesCount
+	} else if count > maxChangesCount {
+		count = maxChangesCount
+	}
+	wait := getTTL(query.Get("wait"), defaultChangesWait)
+	if wait > maxChangesWait {
+		wait = maxChangesWait
+	}
+	if wt := s.web.WriteTimeout; wt > time.Second && wait > wt-time.Second {
+		wait = wt - time.Second
+	}
+
+	select {
+	case s.polls <- struct{}{}:
+		s.metrics.changesPolls.Inc()
+		defer func() {
+			<-s.polls
+			s.metrics.changesPolls.Dec()
+		}()
+	default:
+		w.Header().Set("Retry-After", "1")
+		s.sendError(w, r, errCodeUnavailable, "too many concurrent polls")
+		return
+	}
+
+	// the blocking read holds its connection for up to wait, so it uses a pool of its own
+	var msgs []redis.XMessage
+	streams, err := s.blocking.XRead(r.Context(), &redis.XReadArgs{
+		Streams: []string{key, since},
+		Count:   count,
+		Block:   wait,
+	}).Result()
+	if err != nil && err != redis.Nil {
+		s.sendRedisReadErr(w, r, err)
+		return
+	}
+	for _, stream := range streams {
+		msgs = append(msgs, stream.Messages...)
+	}
+
+	result := struct {
+		Data struct {
+			Last    string   `json:"last"`
+			Changes []change `json:"changes"`
+		} `json:"data"`
+	}{}
+	result.Data.Last = since
+	result.Data.Changes = make([]change, 0, len(msgs))
+	for _, msg := range msgs {
+		result.Data.Changes = append(result.Data.Changes, s.parseChange(r, ksConf, keyspace, msg))
+		result.Data.Last = msg.ID
+	}
+
+	output, _ := json.Marshal(result)
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(output)
+}
+
+// isStreamID reports whether id is a Redis stream ID, <ms>[-<seq>]
+func isStreamID(id string) bool {
+	ms, seq, hasSeq := strings.Cut(id, "-")
+	if _, err := strconv.ParseUint(ms, 10, 64); err != nil {
+		return false
+	}
+	if hasSeq {
+		_, err := strconv.ParseUint(seq, 10, 64)
+		return err == nil
+	}
+	return true
+}
+
+// parseChange converts a stream entry to change, with payload in Output format
+func (s *Server) parseChange(r *http.Request, ksConf *KeyspaceConfig, keyspace string, msg redis.XMessage) change {
+	c := change{StreamID: msg.ID, Keyspace: keyspace}
+	c.ID, _ = msg.Values["id"].(string)
+	c.Op, _ = msg.Values["op"].(string)
+	if v, ok := msg.Values["mb"].(string); ok {
+		c.MagicByte, _ = strconv.Atoi(v)
+	}
+	if v, ok := msg.Values["ttl"].(string); ok {
+		c.TTL, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if v, ok := msg.Values["ts"].(string); ok {
+		c.Timestamp, _ = strconv.ParseInt(v, 10, 64)
+	}
+	payload, _ := msg.Values["payload"].(string)
+	if payload == "" {
+		return c
+	}
+	magicByte, data, err := conversion.ConvertContext(r.Context(), keyspace, magicbyte.NewFrom(byte(c.MagicByte)),
+		ksConf.Output.magicByte, []byte(payload))
+	if err != nil {
+		s.reqLog(r).Error("Conversion error", "keyspace", keyspace, "id", c.ID, "err", err)
+		return c
+	}
+	c.ContentType = magicByte.GetContentType()
+	c.ContentEncoding = magicByte.GetContentEncoding()
+	c.Payload = data
+	return c
+}
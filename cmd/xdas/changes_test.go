@@ -0,0 +1,128 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestIsStreamID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"0", true},
+		{"1700000000000", true},
+		{"1700000000000-0", true},
+		{"1700000000000-12", true},
+		{"", false},
+		{"$", false},
+		{"-", false},
+		{"-1", false},
+		{"1-", false},
+		{"1-x", false},
+		{"abc", false},
+		{"1-2-3", false},
+	}
+	for _, tt := range tests {
+		if got := isStreamID(tt.id); got != tt.want {
+			t.Errorf("isStreamID(%q) got: %v, want: %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestHandleFuncXdasChanges(t *testing.T) {
+	s, _ := newTestServer(t, `{"Changes": {"MaxPolls": 1}, "Keyspaces": {"gs": {"ttl": "1h", "Changes": {"Enabled": true}}, "plain": {"ttl": "1h"}}}`)
+	for _, req := range []struct{ method, id string }{{http.MethodPut, "A"}, {http.MethodPut, "B"}, {http.MethodDelete, "A"}} {
+		if w := serve(s, req.method, "/v2/gs/"+req.id, "x", nil); w.Code != http.StatusOK {
+			t.Fatalf("%s %s got: %d, want: %d", req.method, req.id, w.Code, http.StatusOK)
+		}
+	}
+	var last string
+	if msgs, err := s.redis.XRevRangeN(context.Background(), redisKeyChanges("gs"), "+", "-", 1).Result(); err != nil || len(msgs) != 1 {
+		t.Fatalf("XRevRangeN() got: %v, %v", msgs, err)
+	} else {
+		last = msgs[0].ID
+	}
+
+	tests := []struct {
+		name        string
+		target      string
+		pollsFull   bool
+		wantStatus  int
+		wantCode    string   // error code
+		wantChanges []string // op and id of the changes
+		wantLast    string
+	}{
+		{"all", "/v2/_changes/gs?since=0", false, http.StatusOK, "", []string{"put A", "put B", "del A"}, last},
+		{"count", "/v2/_changes/gs?since=0-0&count=2", false, http.StatusOK, "", []string{"put A", "put B"}, ""},
+		{"none after last", "/v2/_changes/gs?since=" + last + "&wait=10ms", false, http.StatusOK, "", []string{}, last},
+		{"from now", "/v2/_changes/gs?since=$&wait=10ms", false, http.StatusOK, "", []string{}, last},
+		{"no since", "/v2/_changes/gs?wait=10ms", false, http.StatusOK, "", []string{}, last},
+		{"invalid since", "/v2/_changes/gs?since=abc", false, http.StatusBadRequest, errCodeBadRequest, nil, ""},
+		{"invalid since seq", "/v2/_changes/gs?since=1-x", false, http.StatusBadRequest, errCodeBadRequest, nil, ""},
+		{"invalid since without a poll slot", "/v2/_changes/gs?since=abc", true, http.StatusBadRequest, errCodeBadRequest, nil, ""},
+		{"too many polls", "/v2/_changes/gs?since=0", true, http.StatusServiceUnavailable, errCodeUnavailable, nil, ""},
+		{"not enabled", "/v2/_changes/plain", false, http.StatusNotFound, errCodeNotFound, nil, ""},
+		{"unknown keyspace", "/v2/_changes/unknown", false, http.StatusBadRequest, errCodeInvalidKeyspace, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.pollsFull {
+				s.polls <- struct{}{}
+				defer func() { <-s.polls }()
+			}
+			w := serve(s, http.MethodGet, tt.target, "", map[string]string{"Accept": "application/json"})
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status got: %d, want: %d, body: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantCode != "" {
+				var result struct{ Error apiError }
+				if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil || result.Error.Code != tt.wantCode {
+					t.Errorf("error got: %s, want code: %s", w.Body, tt.wantCode)
+				}
+				if tt.wantStatus == http.StatusServiceUnavailable && w.Header().Get("Retry-After") == "" {
+					t.Error("Retry-After got empty")
+				}
+				return
+			}
+			var result struct {
+				Data struct {
+					Last    string
+					Changes []change
+				}
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+				t.Fatalf("body got: %s, error: %v", w.Body, err)
+			}
+			changes := []string{}
+			for _, c := range result.Data.Changes {
+				changes = append(changes, c.Op+" "+c.ID)
+			}
+			if !slices.Equal(changes, tt.wantChanges) {
+				t.Errorf("changes got: %q, want: %q", changes, tt.wantChanges)
+			}
+			if tt.wantLast != "" && result.Data.Last != tt.wantLast {
+				t.Errorf("last got: %s, want: %s", result.Data.Last, tt.wantLast)
+			}
+		})
+	}
+}
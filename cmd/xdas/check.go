@@ -0,0 +1,160 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"text/tabwriter"
+	"xdas/internal/conversion"
+	"xdas/internal/findx"
+	"xdas/internal/keyspaces"
+	"xdas/internal/logger"
+	"xdas/internal/magicbyte"
+	"xdas/internal/notify"
+	"xdas/internal/rediscrypto"
+)
+
+// checkConfigFile runs the same validation as startup, including loading TLS files, plus checks
+// that would otherwise only fail at runtime: encryption keys, protobuf message registration for
+// keyspaces that need conversion or validation, FindX and Notify URLs and Multipart keyspaces.
+// It prints a report of each keyspace's resolved formats to w and returns the number of problems.
+func checkConfigFile(w io.Writer, source configSource) int {
+	var problems int
+	problem := func(format string, a ...any) {
+		problems++
+		fmt.Fprintf(w, "PROBLEM: "+format+"\n", a...)
+	}
+
+	fmt.Fprintln(w, "Config file:", source)
+	config, err := loadConfig(logger.NewLoggerWithIOWriter(os.Stderr), source)
+	if err != nil {
+		problem("%v", err)
+		fmt.Fprintf(w, "%d problem(s) found\n", problems)
+		return problems
+	}
+	if _, err := rediscrypto.NewAesGCM(config.Redis.EncryptionKey); err != nil {
+		problem("Redis EncryptionKey: %v", err)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nKEYSPACE\tKIND\tTTL\tINPUT\tSTORE\tOUTPUT\tENCRYPTION\tFINDX\tNOTIFY\tCHANGES")
+	for _, keyspace := range sortedKeys(config.Keyspaces) {
+		ksConf := config.Keyspaces[keyspace]
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%t\n", keyspace, ksConf.Kind, ksConf.ttl,
+			formatReport(ksConf.Input.magicByte), formatReport(ksConf.Store.magicByte),
+			formatReport(ksConf.Output.magicByte), ksConf.Encryption, findXReport(ksConf.FindX), notifyReport(ksConf.Notify),
+			ksConf.Changes.Enabled)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+
+	for _, keyspace := range sortedKeys(config.Keyspaces) {
+		for _, p := range checkKeyspace(config, keyspace, config.Keyspaces[keyspace]) {
+			problem("keyspace %s: %s", keyspace, p)
+		}
+	}
+	for _, keyspace := range config.Multipart.Keyspaces {
+		if _, ok := config.Keyspaces[keyspace]; !ok {
+			problem("Multipart keyspace %s is not defined", keyspace)
+		}
+	}
+
+	if problems == 0 {
+		fmt.Fprintln(w, "OK")
+	} else {
+		fmt.Fprintf(w, "%d problem(s) found\n", problems)
+	}
+	return problems
+}
+
+// checkKeyspace returns the problems found in ksConf
+func checkKeyspace(config *Configuration, keyspace string, ksConf *KeyspaceConfig) []string {
+	var problems []string
+	in, store, out := ksConf.Input.magicByte, ksConf.Store.magicByte, ksConf.Output.magicByte
+	hasMessage := conversion.HasMessage(keyspace)
+
+	if ksConf.Kind == keyspaces.KSString && !hasMessage {
+		if in.GetCTV() != 0 && store.GetCTV() != 0 && in.GetCTV() != store.GetCTV() {
+			problems = append(problems, "input to store content-type conversion requires a registered protobuf message")
+		}
+		if store.GetCTV() != 0 && out.GetCTV() != 0 && store.GetCTV() != out.GetCTV() {
+			problems = append(problems, "store to output content-type conversion requires a registered protobuf message")
+		}
+		if config.ValidateContent {
+			problems = append(problems, "ValidateContent requires a registered protobuf message, all PUT will be rejected")
+		}
+		if store.GetCEV() == 0 && in.GetCEV() != 0 {
+			problems = append(problems, "compressed input stored uncompressed requires a registered protobuf message")
+		}
+	}
+
+	if f := ksConf.FindX; f.Enabled {
+		if _, err := url.ParseRequestURI(f.URL); err != nil {
+			problems = append(problems, "invalid FindX URL: "+err.Error())
+		}
+		switch f.Queue {
+		case "", findx.QueueChannel, findx.QueueStream:
+		default:
+			problems = append(problems, "unknown FindX queue "+f.Queue)
+		}
+	}
+	if n := ksConf.Notify; n.Enabled {
+		if _, err := url.ParseRequestURI(n.URL); err != nil {
+			problems = append(problems, "invalid Notify URL: "+err.Error())
+		}
+		for _, m := range n.Methods {
+			switch m {
+			case notify.OpPut, notify.OpDel, notify.OpInc:
+			default:
+				problems = append(problems, "unknown Notify method "+m)
+			}
+		}
+	}
+	return problems
+}
+
+func formatReport(m magicbyte.MagicByte) string {
+	s := m.GetContentType()
+	if ce := m.GetContentEncoding(); ce != "" {
+		s += "+" + ce
+	}
+	if m.GetEncryption() != 0 {
+		s += fmt.Sprintf("+enc%d", m.GetEncryption())
+	}
+	return fmt.Sprintf("%s(0x%02x)", s, m.Get())
+}
+
+func findXReport(f *findx.FindX) string {
+	if !f.Enabled {
+		return "-"
+	}
+	queue := f.Queue
+	if queue == "" {
+		queue = findx.QueueChannel
+	}
+	return queue + " " + redactURL(f.URL)
+}
+
+func notifyReport(n *notify.Notify) string {
+	if !n.Enabled {
+		return "-"
+	}
+	return redactURL(n.URL)
+}
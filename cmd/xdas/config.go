@@ -17,31 +17,47 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"time"
+	"xdas/internal/auth"
+	"xdas/internal/cache"
 	"xdas/internal/config"
+	"xdas/internal/conversion"
 	"xdas/internal/findx"
 	"xdas/internal/keyspaces"
 	"xdas/internal/logger"
 	"xdas/internal/magicbyte"
+	"xdas/internal/notify"
+	"xdas/internal/ratelimit"
+	"xdas/internal/store"
+	"xdas/internal/tracing"
 
-	"github.com/thedevop1/jsoncr"
+	"github.com/santhosh-tekuri/jsonschema/v5"
 )
 
 // Configuration holds all the config
 type Configuration struct {
-	// raw             []byte
+	source          configSource
 	Verbose         bool
 	NoMetrics       bool
 	ValidateContent bool
-	Web             *config.WebConfig
-	HClient         *config.HClientConfig
-	Redis           *config.RedisConfig
-	Keyspaces       map[string]*KeyspaceConfig
-	Multipart       struct {
+	// Store is the storage backend, store.BackendRedis (default) or store.BackendMemory for tests
+	// and local development, which doesn't support Watch, Changes, the FindX stream queue and
+	// shared RateLimit
+	Store string
+	// MaxDecompressedSize limits decompressing for conversion and validation, keyspaces can set
+	// their own with maxDecompressedSize, default conversion.DefaultMaxDecompressedSize
+	MaxDecompressedSize int64
+	Web                 *config.WebConfig
+	HClient             *config.HClientConfig
+	Redis               *config.RedisConfig
+	Keyspaces           map[string]*KeyspaceConfig
+	Multipart           struct {
 		Keyspaces []string
 	}
 	DeviceMapping struct {
@@ -50,17 +66,59 @@ type Configuration struct {
 		ttl      time.Duration
 		accelTTL time.Duration
 	}
+	Watch struct {
+		Enabled     bool
+		KeepAlive   string
+		MaxWatchers int // max concurrent watchers, each has a Redis connection, default 1000
+		keepAlive   time.Duration
+	}
+	Changes struct {
+		// MaxPolls is the max concurrent long polls of the changes API, default 100. They hold
+		// Redis connections of their own, not those of the other requests. Bound at startup.
+		MaxPolls int
+	}
+	Health struct {
+		DrainDelay  string // how long /readyz returns 503 before shutting down, default 5s
+		PingTimeout string // timeout of the Redis pings of /readyz, default 1s
+		drainDelay  time.Duration
+		pingTimeout time.Duration
+	}
+	Auth      auth.Auth
+	RateLimit ratelimit.RateLimit
+	Tracing   tracing.Tracing // bound at startup, not reloaded
+	Admin     struct {
+		Token string // bearer token for /admin endpoints, they are disabled if empty
+	}
+	maxSize int64 // the largest maxSize of all keyspaces, the limit of requests before routing
 }
 
 // KeyspaceConfig holds config for keyspace
 type KeyspaceConfig struct {
-	Input     KeyspaceFormat
-	Store     KeyspaceFormat
-	Output    KeyspaceFormat
-	Kind      keyspaces.Kind
-	FindX     *findx.FindX
+	Input   KeyspaceFormat
+	Store   KeyspaceFormat
+	Output  KeyspaceFormat
+	Kind    keyspaces.Kind
+	FindX   *findx.FindX
+	Notify  *notify.Notify
+	Changes *ChangesConfig
+	Cache   *cache.Cache // in-process cache of hot keys, for GET
+	// Encryption policy, see encryptionRequired, encryptionOptional and encryptionDisabled
+	Encryption string `json:"encryption"`
+	// MaxSize is the max request body size, default MaxSize
+	MaxSize int64 `json:"maxSize"`
+	// MaxDecompressedSize limits decompressing data of the keyspace for conversion and validation.
+	// 0 to use the global MaxDecompressedSize.
+	MaxDecompressedSize int64 `json:"maxDecompressedSize"`
+	// Schema is the JSON Schema file that request bodies must validate against, requires JSON input
+	Schema    string `json:"schema"`
+	schema    *jsonschema.Schema
 	TTLString string `json:"ttl"`
 	ttl       time.Duration
+	raw       []byte // compacted JSON of the keyspace config
+	// RedisTimeout bounds the Redis calls of each request, e.g. "50ms". Empty to only apply the
+	// ReadTimeout and WriteTimeout of the Redis ClientConfig.
+	RedisTimeout string `json:"redisTimeout"`
+	redisTimeout time.Duration
 }
 
 // KeyspaceFormat specifies the content-type and content-encoding for keyspace
@@ -72,9 +130,11 @@ type KeyspaceFormat struct {
 
 func getConfig(logger *logger.Logger) *Configuration {
 	var (
-		version    bool
-		configFile = flag.String("config", os.Getenv("XX_CONFIG"), "The config filename, env: XX_CONFIG")
-		verbose    = flag.Bool("verbose", false, "Turn on verbose logging")
+		version     bool
+		configFile  = flag.String("config", os.Getenv("XX_CONFIG"), "The config filenames, JSON with comments or YAML, comma separated and merged in order, env: XX_CONFIG")
+		ksDir       = flag.String("keyspaces-dir", os.Getenv("XX_KEYSPACES_DIR"), "Directory of keyspace config files merged into Keyspaces, env: XX_KEYSPACES_DIR")
+		verbose     = flag.Bool("verbose", false, "Turn on verbose logging")
+		checkConfig = flag.Bool("check-config", false, "Validate the config, print a report and exit, non-zero if there is any problem")
 	)
 
 	flag.BoolVar(&version, "v", false, "Shows version and exit")
@@ -85,27 +145,17 @@ func getConfig(logger *logger.Logger) *Configuration {
 		fmt.Println(AppName, AppVersion, BuildTime)
 		os.Exit(0)
 	}
-
-	config := &Configuration{
-		Web:     config.NewWeb(),
-		HClient: config.NewHClient(),
-		Redis:   config.NewRedis(),
+	source := newConfigSource(*configFile, *ksDir)
+	if *checkConfig {
+		if problems := checkConfigFile(os.Stdout, source); problems > 0 {
+			os.Exit(1)
+		}
+		os.Exit(0)
 	}
 
-	if *configFile != "" {
-		file, err := os.Open(*configFile)
-		if err != nil {
-			logger.Fatal(err)
-		}
-		defer file.Close()
-		b, err := jsoncr.Remove(file)
-		if err != nil {
-			logger.Fatal(err)
-		}
-		if err = json.Unmarshal(b, &config); err != nil {
-			logger.Fatal(err)
-		}
-		// config.raw = b
+	config, err := loadConfig(logger, source)
+	if err != nil {
+		logger.Fatal(err)
 	}
 	flag.Visit(func(f *flag.Flag) {
 		if f.Name == "verbose" {
@@ -113,26 +163,106 @@ func getConfig(logger *logger.Logger) *Configuration {
 		}
 	})
 
+	logger.Info("Web server", "config", fmt.Sprint(config.Web.Server))
+	logger.Info("HClient", "config", fmt.Sprint(config.HClient.Client.Transport))
+	logger.Info("Redis", "config", config.Redis) // secrets are redacted by RedisConfig.LogValue
+	// logger.Println("Config:", config)
+	// logger.Println("WebTLS Config:", config.WebTLS)
+
+	return config
+}
+
+// loadConfig reads the config files and validates it, it is used both at startup and on reload
+func loadConfig(logger *logger.Logger, source configSource) (*Configuration, error) {
+	config := newConfiguration()
+	config.source = source
+
+	if !source.empty() {
+		b, err := readConfig(source)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(b, &config); err != nil {
+			return nil, err
+		}
+		// keep the raw keyspace config to tell which keyspaces are changed on reload
+		var raw struct{ Keyspaces map[string]json.RawMessage }
+		if err = json.Unmarshal(b, &raw); err != nil {
+			return nil, err
+		}
+		for keyspace, ksConf := range config.Keyspaces {
+			var buf bytes.Buffer
+			json.Compact(&buf, raw.Keyspaces[keyspace])
+			ksConf.raw = buf.Bytes()
+		}
+	}
+
 	if err := config.Web.Validate(); err != nil {
-		logger.Fatal("Web server config error", "err", err)
+		return nil, fmt.Errorf("Web server config error: %w", err)
 	}
 	if err := config.HClient.Validate(); err != nil {
-		logger.Fatal("HTTP client config error", "err", err)
+		return nil, fmt.Errorf("HTTP client config error: %w", err)
+	}
+	if err := config.Auth.Validate(); err != nil {
+		return nil, fmt.Errorf("Auth config error: %w", err)
 	}
-	if err := config.Redis.Validate(); err != nil {
-		logger.Fatal("Redis config error", "err", err)
+	if err := config.RateLimit.Validate(); err != nil {
+		return nil, fmt.Errorf("RateLimit config error: %w", err)
+	}
+	if err := config.Tracing.Validate(); err != nil {
+		return nil, fmt.Errorf("Tracing config error: %w", err)
+	}
+	if err := validateKeyspaceConfig(config); err != nil {
+		return nil, err
+	}
+	if err := validateStoreConfig(config); err != nil {
+		return nil, err
 	}
-	validateKeyspaceConfig(logger, config)
 	validateDeviceMappingConfig(logger, config)
+	validateWatchConfig(logger, config)
+	if config.Changes.MaxPolls < 1 {
+		config.Changes.MaxPolls = defaultChangesMaxPolls
+	}
+	validateHealthConfig(logger, config)
+	return config, nil
+}
 
-	logger.Info("Web server", "config", fmt.Sprint(config.Web.Server))
-	logger.Info("HClient", "config", fmt.Sprint(config.HClient.Client.Transport))
-	// logger.Println("Redis addr:", config.Redis.ClientConfig.Addrs)
-	// logger.Println("Redis client config", config.Redis.ClientConfig)
-	// logger.Println("Config:", config)
-	// logger.Println("WebTLS Config:", config.WebTLS)
+// validateStoreConfig checks the Redis config, or that the memory store is not used with
+// features that require Redis
+func validateStoreConfig(config *Configuration) error {
+	switch config.Store {
+	case "", store.BackendRedis:
+		config.Store = store.BackendRedis
+		if err := config.Redis.Validate(); err != nil {
+			return fmt.Errorf("Redis config error: %w", err)
+		}
+		return nil
+	case store.BackendMemory:
+	default:
+		return fmt.Errorf("invalid Store %q, must be %s or %s", config.Store, store.BackendRedis, store.BackendMemory)
+	}
 
-	return config
+	if err := config.Redis.ValidateEncryption(); err != nil {
+		return fmt.Errorf("Redis config error: %w", err)
+	}
+	if config.Watch.Enabled {
+		return errors.New("Watch requires the Redis store")
+	}
+	if config.RateLimit.Enabled && config.RateLimit.Shared {
+		return errors.New("shared RateLimit requires the Redis store")
+	}
+	for keyspace, ksConf := range config.Keyspaces {
+		if ksConf.Changes != nil && ksConf.Changes.Enabled {
+			return fmt.Errorf("keyspace %s: Changes requires the Redis store", keyspace)
+		}
+		if ksConf.FindX.Enabled && ksConf.FindX.Queue == findx.QueueStream {
+			return fmt.Errorf("keyspace %s: FindX stream queue requires the Redis store", keyspace)
+		}
+		if ksConf.Cache.Enabled && ksConf.Cache.Invalidate {
+			return fmt.Errorf("keyspace %s: Cache Invalidate requires the Redis store", keyspace)
+		}
+	}
+	return nil
 }
 
 func validateDeviceMappingConfig(logger *logger.Logger, config *Configuration) {
@@ -151,7 +281,46 @@ func validateDeviceMappingConfig(logger *logger.Logger, config *Configuration) {
 	config.DeviceMapping.accelTTL = accelTTL
 }
 
-func validateKeyspaceConfig(logger *logger.Logger, config *Configuration) {
+func validateWatchConfig(logger *logger.Logger, config *Configuration) {
+	if config.Watch.MaxWatchers < 1 {
+		config.Watch.MaxWatchers = defaultMaxWatchers
+	}
+	config.Watch.keepAlive = defaultWatchKeepAlive
+	if config.Watch.KeepAlive == "" {
+		return
+	}
+	keepAlive, err := time.ParseDuration(config.Watch.KeepAlive)
+	if err != nil || keepAlive <= 0 {
+		logger.Info("Invalid Watch KeepAlive", "keepAlive", config.Watch.KeepAlive, "err", err)
+		return
+	}
+	config.Watch.keepAlive = keepAlive
+}
+
+func validateHealthConfig(logger *logger.Logger, config *Configuration) {
+	config.Health.drainDelay = defaultDrainDelay
+	config.Health.pingTimeout = defaultPingTimeout
+	if d, err := time.ParseDuration(config.Health.DrainDelay); config.Health.DrainDelay != "" {
+		if err != nil || d < 0 {
+			logger.Info("Invalid Health DrainDelay", "drainDelay", config.Health.DrainDelay, "err", err)
+		} else {
+			config.Health.drainDelay = d
+		}
+	}
+	if d, err := time.ParseDuration(config.Health.PingTimeout); config.Health.PingTimeout != "" {
+		if err != nil || d <= 0 {
+			logger.Info("Invalid Health PingTimeout", "pingTimeout", config.Health.PingTimeout, "err", err)
+		} else {
+			config.Health.pingTimeout = d
+		}
+	}
+}
+
+func validateKeyspaceConfig(config *Configuration) error {
+	config.maxSize = MaxSize
+	if config.MaxDecompressedSize < 1 {
+		config.MaxDecompressedSize = conversion.DefaultMaxDecompressedSize
+	}
 	for key, value := range config.Keyspaces {
 		value.Input.magicByte = magicbyte.New(value.Input.ContentEncoding, value.Input.ContentType, 0)
 
@@ -161,8 +330,15 @@ func validateKeyspaceConfig(logger *logger.Logger, config *Configuration) {
 		if value.Store.ContentType == "" {
 			value.Store.ContentType = value.Input.ContentType
 		}
+		if err := compileSchema(value); err != nil {
+			return fmt.Errorf("KeyspaceConfig error, %s schema: %w", key, err)
+		}
+		encryption, err := validateEncryptionPolicy(config, value)
+		if err != nil {
+			return fmt.Errorf("KeyspaceConfig error, %s: %w", key, err)
+		}
 		value.Store.magicByte = magicbyte.New(value.Store.ContentEncoding, value.Store.ContentType,
-			config.Redis.Encryption) // ensure encryption at rest
+			encryption) // ensure encryption at rest
 
 		if value.Store.ContentType == "" { // can't have different output contentType when stored is unknown
 			value.Output.ContentType = ""
@@ -180,10 +356,57 @@ func validateKeyspaceConfig(logger *logger.Logger, config *Configuration) {
 		}
 		value.FindX.UserAgent = AppName
 
+		if value.Notify == nil {
+			value.Notify = new(notify.Notify)
+		}
+		value.Notify.UserAgent = AppName
+
+		if value.Changes == nil {
+			value.Changes = new(ChangesConfig)
+		}
+		if value.Changes.MaxLen < 1 {
+			value.Changes.MaxLen = defaultChangesMaxLen
+		}
+
+		if value.Cache == nil {
+			value.Cache = new(cache.Cache)
+		}
+		if err := value.Cache.Validate(); err != nil {
+			return fmt.Errorf("KeyspaceConfig error, %s cache: %w", key, err)
+		}
+
+		if value.MaxSize < 1 {
+			value.MaxSize = MaxSize
+		}
+		config.maxSize = max(config.maxSize, value.MaxSize)
+		if value.MaxDecompressedSize < 0 {
+			value.MaxDecompressedSize = 0
+		}
+
 		ttl, err := time.ParseDuration(value.TTLString)
 		if err != nil {
-			logger.Fatal("KeyspaceConfig error, must have valid TTL", key, value, err)
+			return fmt.Errorf("KeyspaceConfig error, %s must have valid TTL: %w", key, err)
 		}
 		value.ttl = ttl
+
+		if value.RedisTimeout != "" {
+			timeout, err := time.ParseDuration(value.RedisTimeout)
+			if err != nil || timeout <= 0 {
+				return fmt.Errorf("KeyspaceConfig error, %s must have valid redisTimeout: %v", key, value.RedisTimeout)
+			}
+			value.redisTimeout = timeout
+		}
+	}
+	return nil
+}
+
+// decompressLimits returns the maxDecompressedSize of the keyspaces that set one
+func (c *Configuration) decompressLimits() map[string]int64 {
+	limits := make(map[string]int64)
+	for keyspace, ksConf := range c.Keyspaces {
+		if ksConf.MaxDecompressedSize > 0 {
+			limits[keyspace] = ksConf.MaxDecompressedSize
+		}
 	}
+	return limits
 }
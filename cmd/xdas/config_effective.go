@@ -0,0 +1,386 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"xdas/internal/auth"
+	"xdas/internal/config"
+	"xdas/internal/findx"
+	"xdas/internal/magicbyte"
+	"xdas/internal/ratelimit"
+)
+
+// redacted replaces secrets in the effective config
+const redacted = "REDACTED"
+
+// effectiveConfig is the config after validation, with derived values and secrets redacted
+type effectiveConfig struct {
+	Source          configSource
+	Verbose         bool
+	NoMetrics       bool
+	ValidateContent bool
+	// MaxDecompressedSize applies to keyspaces with MaxDecompressedSize 0
+	MaxDecompressedSize int64
+	Store               string
+	Web                 effectiveWeb
+	HClient             effectiveHClient
+	Redis               effectiveRedis
+	Keyspaces           map[string]effectiveKeyspace
+	Multipart           struct{ Keyspaces []string }
+	DeviceMapping       struct{ TTL, AccelTTL string }
+	Watch               struct {
+		Enabled     bool
+		KeepAlive   string
+		MaxWatchers int
+	}
+	Changes   struct{ MaxPolls int }
+	Health    struct{ DrainDelay, PingTimeout string }
+	Auth      effectiveAuth
+	RateLimit struct {
+		Enabled    bool
+		By         string
+		Shared     bool
+		Limits     []ratelimit.Limit
+		MaxBuckets int
+	}
+	Tracing struct {
+		Enabled     bool
+		Exporter    string
+		Endpoint    string
+		Headers     map[string]string
+		File        string
+		SampleRatio float64
+	}
+	Admin struct{ Token string }
+}
+
+type effectiveAuth struct {
+	Enabled    bool
+	MaxSkew    string
+	Principals map[string]effectivePrincipal
+	JWT        auth.JWT // has no secrets
+}
+
+type effectivePrincipal struct {
+	APIKeys      []string
+	HMACKeys     []string
+	CertSubjects []string
+	Permissions  map[string][]auth.Permission
+}
+
+type effectiveTLS struct {
+	CertFile string
+	KeyFile  string
+	CaFile   string
+	Insecure bool
+}
+
+type effectiveWeb struct {
+	Addr              string
+	ReadTimeout       string
+	ReadHeaderTimeout string
+	WriteTimeout      string
+	IdleTimeout       string
+	MaxHeaderBytes    int
+	TLS               effectiveTLS
+}
+
+type effectiveHClient struct {
+	Timeout string
+	TLS     effectiveTLS
+}
+
+type effectiveRedis struct {
+	Addrs           []string
+	DB              int
+	Protocol        int
+	Username        string
+	Password        string
+	PasswordSource  string
+	MaxRetries      int
+	DialTimeout     string
+	ReadTimeout     string
+	WriteTimeout    string
+	PoolSize        int
+	MinIdleConns    int
+	MaxIdleConns    int
+	PoolTimeout     string
+	ConnMaxIdleTime string
+	ConnMaxLifetime string
+	MaxRedirects    int
+	ReadOnly        bool
+	RouteByLatency  bool
+	RouteRandomly   bool
+	MasterName      string
+	TLS             bool
+	Encryption      int
+	EncryptionKey   []string
+	KeySource       string
+}
+
+type effectiveKeyspace struct {
+	Kind       string
+	TTL        string
+	Input      effectiveFormat
+	Store      effectiveFormat
+	Output     effectiveFormat
+	Encryption string
+	MaxSize    int64
+	// MaxDecompressedSize is 0 if the global MaxDecompressedSize applies
+	MaxDecompressedSize int64
+	Schema              string
+	RedisTimeout        string
+	FindX               effectiveFindX
+	Notify              effectiveNotify
+	Changes             ChangesConfig
+	Cache               effectiveCache
+}
+
+type effectiveFormat struct {
+	ContentType     string
+	ContentEncoding string
+	Encryption      int
+	MagicByte       byte
+}
+
+type effectiveFindX struct {
+	Enabled           bool
+	URL               string
+	Queue             string
+	ChannelBufferSize int
+	Thread            int
+	Stream            findx.Stream
+}
+
+type effectiveCache struct {
+	Enabled      bool
+	MaxEntries   int
+	MaxValueSize int
+	TTL          string
+	Invalidate   bool
+}
+
+type effectiveNotify struct {
+	Enabled           bool
+	URL               string
+	Methods           []string
+	IncludeBody       bool
+	ChannelBufferSize int
+	Thread            int
+	MaxRetry          int
+	RetryWait         string
+}
+
+// newEffectiveConfig returns the effective config of c
+func newEffectiveConfig(c *Configuration) *effectiveConfig {
+	e := &effectiveConfig{
+		Source:              c.source,
+		Verbose:             c.Verbose,
+		NoMetrics:           c.NoMetrics,
+		ValidateContent:     c.ValidateContent,
+		MaxDecompressedSize: c.MaxDecompressedSize,
+		Store:               c.Store,
+		Keyspaces:           make(map[string]effectiveKeyspace, len(c.Keyspaces)),
+	}
+
+	web := c.Web.Server
+	e.Web = effectiveWeb{
+		Addr:              web.Addr,
+		ReadTimeout:       web.ReadTimeout.String(),
+		ReadHeaderTimeout: web.ReadHeaderTimeout.String(),
+		WriteTimeout:      web.WriteTimeout.String(),
+		IdleTimeout:       web.IdleTimeout.String(),
+		MaxHeaderBytes:    web.MaxHeaderBytes,
+		TLS:               newEffectiveTLS(c.Web.TLS),
+	}
+	e.HClient = effectiveHClient{
+		Timeout: c.HClient.Client.Timeout.String(),
+		TLS:     newEffectiveTLS(c.HClient.TLS),
+	}
+
+	rc := c.Redis.ClientConfig
+	e.Redis = effectiveRedis{
+		Addrs:           rc.Addrs,
+		DB:              rc.DB,
+		Protocol:        rc.Protocol,
+		Username:        rc.Username,
+		Password:        redact(rc.Password),
+		PasswordSource:  c.Redis.PasswordSource(),
+		MaxRetries:      rc.MaxRetries,
+		DialTimeout:     rc.DialTimeout.String(),
+		ReadTimeout:     rc.ReadTimeout.String(),
+		WriteTimeout:    rc.WriteTimeout.String(),
+		PoolSize:        rc.PoolSize,
+		MinIdleConns:    rc.MinIdleConns,
+		MaxIdleConns:    rc.MaxIdleConns,
+		PoolTimeout:     rc.PoolTimeout.String(),
+		ConnMaxIdleTime: rc.ConnMaxIdleTime.String(),
+		ConnMaxLifetime: rc.ConnMaxLifetime.String(),
+		MaxRedirects:    rc.MaxRedirects,
+		ReadOnly:        rc.ReadOnly,
+		RouteByLatency:  rc.RouteByLatency,
+		RouteRandomly:   rc.RouteRandomly,
+		MasterName:      rc.MasterName,
+		TLS:             rc.TLSConfig != nil,
+		Encryption:      c.Redis.Encryption,
+		EncryptionKey:   redactAll(c.Redis.EncryptionKey),
+		KeySource:       c.Redis.EncryptionKeySource(),
+	}
+
+	for keyspace, ksConf := range c.Keyspaces {
+		e.Keyspaces[keyspace] = newEffectiveKeyspace(ksConf)
+	}
+	e.Multipart.Keyspaces = c.Multipart.Keyspaces
+	e.DeviceMapping.TTL = c.DeviceMapping.ttl.String()
+	e.DeviceMapping.AccelTTL = c.DeviceMapping.accelTTL.String()
+	e.Watch.Enabled = c.Watch.Enabled
+	e.Watch.KeepAlive = c.Watch.keepAlive.String()
+	e.Watch.MaxWatchers = c.Watch.MaxWatchers
+	e.Changes.MaxPolls = c.Changes.MaxPolls
+	e.Health.DrainDelay = c.Health.drainDelay.String()
+	e.Health.PingTimeout = c.Health.pingTimeout.String()
+	e.Auth = effectiveAuth{
+		Enabled:    c.Auth.Enabled,
+		MaxSkew:    c.Auth.MaxSkew.String(),
+		JWT:        c.Auth.JWT,
+		Principals: make(map[string]effectivePrincipal, len(c.Auth.Principals)),
+	}
+	for name, p := range c.Auth.Principals {
+		e.Auth.Principals[name] = effectivePrincipal{
+			APIKeys:      redactAll(p.APIKeys),
+			HMACKeys:     redactAll(p.HMACKeys),
+			CertSubjects: p.CertSubjects,
+			Permissions:  p.Permissions,
+		}
+	}
+	e.RateLimit.Enabled = c.RateLimit.Enabled
+	e.RateLimit.By = c.RateLimit.By
+	e.RateLimit.Shared = c.RateLimit.Shared
+	e.RateLimit.Limits = c.RateLimit.Limits
+	e.RateLimit.MaxBuckets = c.RateLimit.MaxBuckets
+	e.Tracing.Enabled = c.Tracing.Enabled
+	e.Tracing.Exporter = c.Tracing.Exporter
+	e.Tracing.Endpoint = redactURL(c.Tracing.Endpoint)
+	e.Tracing.Headers = make(map[string]string, len(c.Tracing.Headers))
+	for k, v := range c.Tracing.Headers {
+		e.Tracing.Headers[k] = redact(v) // usually credentials
+	}
+	e.Tracing.File = c.Tracing.File
+	e.Tracing.SampleRatio = c.Tracing.SampleRatio
+	e.Admin.Token = redact(c.Admin.Token)
+	return e
+}
+
+func newEffectiveKeyspace(ksConf *KeyspaceConfig) effectiveKeyspace {
+	f, n := ksConf.FindX, ksConf.Notify
+	return effectiveKeyspace{
+		Kind:                ksConf.Kind.String(),
+		TTL:                 ksConf.ttl.String(),
+		Input:               newEffectiveFormat(ksConf.Input.magicByte),
+		Store:               newEffectiveFormat(ksConf.Store.magicByte),
+		Output:              newEffectiveFormat(ksConf.Output.magicByte),
+		Encryption:          ksConf.Encryption,
+		MaxSize:             ksConf.MaxSize,
+		MaxDecompressedSize: ksConf.MaxDecompressedSize,
+		Schema:              ksConf.Schema,
+		RedisTimeout:        ksConf.redisTimeout.String(),
+		FindX: effectiveFindX{
+			Enabled:           f.Enabled,
+			URL:               redactURL(f.URL),
+			Queue:             f.Queue,
+			ChannelBufferSize: f.ChannelBufferSize,
+			Thread:            f.Thread,
+			Stream:            f.Stream,
+		},
+		Notify: effectiveNotify{
+			Enabled:           n.Enabled,
+			URL:               redactURL(n.URL),
+			Methods:           n.Methods,
+			IncludeBody:       n.IncludeBody,
+			ChannelBufferSize: n.ChannelBufferSize,
+			Thread:            n.Thread,
+			MaxRetry:          n.MaxRetry,
+			RetryWait:         n.RetryWait.String(),
+		},
+		Changes: *ksConf.Changes,
+		Cache: effectiveCache{
+			Enabled:      ksConf.Cache.Enabled,
+			MaxEntries:   ksConf.Cache.MaxEntries,
+			MaxValueSize: ksConf.Cache.MaxValueSize,
+			TTL:          ksConf.Cache.TTL.String(),
+			Invalidate:   ksConf.Cache.Invalidate,
+		},
+	}
+}
+
+func newEffectiveFormat(m magicbyte.MagicByte) effectiveFormat {
+	return effectiveFormat{
+		ContentType:     m.GetContentType(),
+		ContentEncoding: m.GetContentEncoding(),
+		Encryption:      m.GetEncryption(),
+		MagicByte:       m.Get(),
+	}
+}
+
+func newEffectiveTLS(t config.TLS) effectiveTLS {
+	return effectiveTLS{CertFile: t.CertFile, KeyFile: t.KeyFile, CaFile: t.CaFile, Insecure: t.Insecure}
+}
+
+// redact returns redacted if s is set, so it is still visible whether a secret is configured
+func redact(s string) string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// redactAll redacts each of ss
+func redactAll(ss []string) []string {
+	r := make([]string, len(ss))
+	for i, s := range ss {
+		r[i] = redact(s)
+	}
+	return r
+}
+
+// redactURL removes the password from u, if any
+func redactURL(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return redact(u)
+	}
+	return parsed.Redacted()
+}
+
+// handleAdminConfig returns the effective config with secrets redacted
+func (s *Server) handleAdminConfig(w http.ResponseWriter, r *http.Request) {
+	result := struct {
+		Data *effectiveConfig `json:"data"`
+	}{newEffectiveConfig(s.cfg())}
+	output, err := json.Marshal(result)
+	if err != nil {
+		s.reqLog(r).Error("Config marshal error", "err", err)
+		s.sendError(w, r, errCodeInternal, "error encoding config")
+		return
+	}
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(output)
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"xdas/internal/config"
+
+	"github.com/thedevop1/jsoncr"
+	"gopkg.in/yaml.v3"
+)
+
+// configSource lists where the config is loaded from
+type configSource struct {
+	Files        []string // merged in order, later files override earlier ones
+	KeyspacesDir string   // keyspace fragments, merged into Keyspaces after Files in filename order
+}
+
+// newConfigSource parses a comma separated list of files
+func newConfigSource(files, keyspacesDir string) configSource {
+	var source configSource
+	for _, f := range strings.Split(files, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			source.Files = append(source.Files, f)
+		}
+	}
+	source.KeyspacesDir = keyspacesDir
+	return source
+}
+
+func (c configSource) empty() bool {
+	return len(c.Files) == 0 && c.KeyspacesDir == ""
+}
+
+func (c configSource) String() string {
+	s := strings.Join(c.Files, ",")
+	if c.KeyspacesDir != "" {
+		s += " keyspaces: " + c.KeyspacesDir
+	}
+	return s
+}
+
+// readConfig reads all the files of the source and merges them into one JSON document. Objects are
+// merged key by key, any other value (including arrays) in a later file replaces the earlier one.
+// Each file is checked on its own first, so errors point at the file and field that failed.
+func readConfig(source configSource) ([]byte, error) {
+	merged := make(map[string]any)
+	for _, name := range source.Files {
+		m, err := readConfigFile(name, newConfiguration(), "")
+		if err != nil {
+			return nil, err
+		}
+		mergeConfig(merged, m)
+	}
+
+	if source.KeyspacesDir != "" {
+		names, err := keyspaceFragments(source.KeyspacesDir)
+		if err != nil {
+			return nil, err
+		}
+		keyspaces, _ := merged["Keyspaces"].(map[string]any)
+		if keyspaces == nil {
+			keyspaces = make(map[string]any)
+			merged["Keyspaces"] = keyspaces
+		}
+		for _, name := range names {
+			m, err := readConfigFile(name, &map[string]*KeyspaceConfig{}, "Keyspaces.")
+			if err != nil {
+				return nil, err
+			}
+			mergeConfig(keyspaces, m)
+		}
+	}
+	return json.Marshal(merged)
+}
+
+// readConfigFile decodes a YAML (.yaml, .yml) or JSON with comments file, and checks that it
+// unmarshals into v
+func readConfigFile(name string, v any, fieldPrefix string) (map[string]any, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		if err = yaml.Unmarshal(b, &m); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if b, err = json.Marshal(m); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	default:
+		if b, err = jsoncr.Remove(bytes.NewReader(b)); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.UseNumber() // keep large integers, e.g. durations in ns, as is
+		if err = d.Decode(&m); err != nil {
+			return nil, configFileError(name, b, fieldPrefix, err)
+		}
+	}
+
+	if err = json.Unmarshal(b, v); err != nil {
+		return nil, configFileError(name, b, fieldPrefix, err)
+	}
+	return m, nil
+}
+
+// configFileError adds the file name and the failed field or position to err
+func configFileError(name string, b []byte, fieldPrefix string, err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		start, end := max(syntaxErr.Offset-30, 0), min(syntaxErr.Offset, int64(len(b)))
+		return fmt.Errorf("%s: %w, near: %s", name, err, b[start:end])
+	case errors.As(err, &typeErr):
+		return fmt.Errorf("%s: field %s%s: cannot use %s as %s", name, fieldPrefix, typeErr.Field,
+			typeErr.Value, typeErr.Type)
+	}
+	return fmt.Errorf("%s: %w", name, err)
+}
+
+// keyspaceFragments returns the config files in dir, sorted by name. Each file holds keyspace
+// configs by keyspace name, like Keyspaces. Hidden files are skipped.
+func keyspaceFragments(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".json", ".jsonc", ".yaml", ".yml":
+			names = append(names, filepath.Join(dir, e.Name()))
+		}
+	}
+	return names, nil
+}
+
+// mergeConfig merges src into dst
+func mergeConfig(dst, src map[string]any) {
+	for k, v := range src {
+		if s, ok := v.(map[string]any); ok {
+			if d, ok := dst[k].(map[string]any); ok {
+				mergeConfig(d, s)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
+
+// newConfiguration returns a Configuration with defaults
+func newConfiguration() *Configuration {
+	return &Configuration{
+		Web:     config.NewWeb(),
+		HClient: config.NewHClient(),
+		Redis:   config.NewRedis(),
+	}
+}
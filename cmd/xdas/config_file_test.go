@@ -0,0 +1,131 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		dst  string
+		src  string
+		want string
+	}{
+		{"add", `{"a": 1}`, `{"b": 2}`, `{"a": 1, "b": 2}`},
+		{"override", `{"a": 1, "b": 2}`, `{"a": 3}`, `{"a": 3, "b": 2}`},
+		{"nested", `{"a": {"b": 1, "c": 2}}`, `{"a": {"c": 3, "d": 4}}`, `{"a": {"b": 1, "c": 3, "d": 4}}`},
+		{"array replaced", `{"a": [1, 2]}`, `{"a": [3]}`, `{"a": [3]}`},
+		{"object replaces value", `{"a": 1}`, `{"a": {"b": 2}}`, `{"a": {"b": 2}}`},
+		{"value replaces object", `{"a": {"b": 2}}`, `{"a": null}`, `{"a": null}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var dst, src, want map[string]any
+			json.Unmarshal([]byte(tt.dst), &dst)
+			json.Unmarshal([]byte(tt.src), &src)
+			json.Unmarshal([]byte(tt.want), &want)
+			mergeConfig(dst, src)
+			if !reflect.DeepEqual(dst, want) {
+				t.Errorf("mergeConfig() got: %v, want: %v", dst, want)
+			}
+		})
+	}
+}
+
+func TestReadConfig(t *testing.T) {
+	dir := t.TempDir()
+	base := writeTestFile(t, dir, "base.jsonc", `{
+		// comments are allowed
+		"ValidateContent": true,
+		"Multipart": {"Keyspaces": ["a", "b"]},
+		"Keyspaces": {"a": {"ttl": "1h", "maxSize": 100}, "b": {"ttl": "1h"}}
+	}`)
+	env := writeTestFile(t, dir, "env.yaml", "Multipart:\n  Keyspaces: [c]\nKeyspaces:\n  a:\n    ttl: 2h\n")
+	fragments := filepath.Join(dir, "keyspaces")
+	if err := os.Mkdir(fragments, 0700); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, fragments, "1.json", `{"c": {"ttl": "1h"}, "b": {"ttl": "3h"}}`)
+	writeTestFile(t, fragments, "2.yml", "c:\n  ttl: 4h\n")
+	writeTestFile(t, fragments, ".hidden.json", `{"d": {"ttl": "1h"}}`)
+	writeTestFile(t, fragments, "notes.txt", "not a config")
+	invalid := filepath.Join(dir, "invalid")
+	if err := os.Mkdir(invalid, 0700); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, invalid, "bad.json", `{"c": {"ttl": 1}}`)
+	syntax := writeTestFile(t, dir, "syntax.json", `{"ValidateContent": true,}`)
+	typ := writeTestFile(t, dir, "type.yaml", "Keyspaces:\n  a:\n    maxSize: big\n")
+
+	tests := []struct {
+		name    string
+		source  configSource
+		want    string // JSON of the merged config
+		wantErr string // substring of the error
+	}{
+		{"single", configSource{Files: []string{base}}, `{
+			"ValidateContent": true,
+			"Multipart": {"Keyspaces": ["a", "b"]},
+			"Keyspaces": {"a": {"ttl": "1h", "maxSize": 100}, "b": {"ttl": "1h"}}
+		}`, ""},
+		{"layered", configSource{Files: []string{base, env}}, `{
+			"ValidateContent": true,
+			"Multipart": {"Keyspaces": ["c"]},
+			"Keyspaces": {"a": {"ttl": "2h", "maxSize": 100}, "b": {"ttl": "1h"}}
+		}`, ""},
+		{"fragments", configSource{Files: []string{base, env}, KeyspacesDir: fragments}, `{
+			"ValidateContent": true,
+			"Multipart": {"Keyspaces": ["c"]},
+			"Keyspaces": {"a": {"ttl": "2h", "maxSize": 100}, "b": {"ttl": "3h"}, "c": {"ttl": "4h"}}
+		}`, ""},
+		{"fragments only", configSource{KeyspacesDir: fragments}, `{
+			"Keyspaces": {"b": {"ttl": "3h"}, "c": {"ttl": "4h"}}
+		}`, ""},
+		{"missing file", configSource{Files: []string{filepath.Join(dir, "missing.json")}}, "", "missing.json"},
+		{"syntax error", configSource{Files: []string{base, syntax}}, "", "syntax.json: "},
+		{"type error", configSource{Files: []string{base, typ}}, "", "type.yaml: field Keyspaces.a.maxSize: cannot use string as int64"},
+		{"fragment type error", configSource{KeyspacesDir: invalid}, "", "bad.json: field Keyspaces.c.ttl: cannot use number as string"},
+		{"missing dir", configSource{KeyspacesDir: filepath.Join(dir, "missing")}, "", "missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := readConfig(tt.source)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("readConfig() got error: %v, want: %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("readConfig() returned error:", err)
+			}
+			var got, want any
+			json.Unmarshal(b, &got)
+			json.Unmarshal([]byte(tt.want), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("readConfig() got: %s, want: %s", b, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"xdas/internal/keyspaces"
+	"xdas/internal/magicbyte"
+)
+
+// Encryption policies of a keyspace
+const (
+	encryptionRequired = "required" // stored encrypted, plaintext records are not served
+	encryptionOptional = "optional" // stored encrypted, plaintext records are served
+	encryptionDisabled = "disabled" // stored as plaintext, encrypted records are still served
+)
+
+// validateEncryptionPolicy defaults and validates the encryption policy of ksConf, and returns
+// the encryption to store records with. The default is optional, or disabled for atomic
+// keyspaces and when Redis.Encryption is 0.
+func validateEncryptionPolicy(config *Configuration, ksConf *KeyspaceConfig) (int, error) {
+	if ksConf.Encryption == "" {
+		ksConf.Encryption = encryptionOptional
+		if ksConf.Kind == keyspaces.KSAtomic || config.Redis.Encryption == 0 {
+			ksConf.Encryption = encryptionDisabled
+		}
+	}
+
+	switch ksConf.Encryption {
+	case encryptionRequired, encryptionOptional:
+		if ksConf.Kind == keyspaces.KSAtomic {
+			return 0, errors.New("atomic keyspaces are stored as plaintext, encryption must be disabled")
+		}
+		if config.Redis.Encryption == 0 {
+			return 0, fmt.Errorf("encryption %s requires Redis Encryption", ksConf.Encryption)
+		}
+		return config.Redis.Encryption, nil
+	case encryptionDisabled:
+		return 0, nil
+	}
+	return 0, fmt.Errorf("invalid encryption %q, must be %s, %s or %s", ksConf.Encryption,
+		encryptionRequired, encryptionOptional, encryptionDisabled)
+}
+
+// allowRead reports whether a record stored as magicByte can be served by the keyspace
+// encryption policy. Plaintext records read from keyspaces that require encryption are counted.
+func (s *Server) allowRead(r *http.Request, ksConf *KeyspaceConfig, keyspace, key string, magicByte magicbyte.MagicByte) bool {
+	if ksConf.Encryption != encryptionRequired || magicByte.GetEncryption() != 0 {
+		return true
+	}
+	s.metrics.plaintextRead.WithLabelValues(keyspace).Inc()
+	s.reqLog(r).Error("Plaintext record in keyspace that requires encryption", "keyspace", keyspace, "key", key)
+	return false
+}
+
+func (s *Server) sendEncryptionPolicyErr(w http.ResponseWriter, r *http.Request) {
+	s.sendError(w, r, errCodeEncryptionPolicy, "record is not encrypted as required by the keyspace")
+}
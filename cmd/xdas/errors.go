@@ -0,0 +1,160 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"xdas/internal/conversion"
+	"xdas/internal/requestid"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Error codes of the API, see errorCodes
+const (
+	errCodeBadRequest       = "bad_request"
+	errCodeInvalidKeyspace  = "invalid_keyspace"
+	errCodeUnsupportedMedia = "unsupported_media_type"
+	errCodeNotAcceptable    = "not_acceptable"
+	errCodeInvalidContent   = "invalid_content"
+	errCodeSchemaViolation  = "schema_violation"
+	errCodeTooLarge         = "payload_too_large"
+	errCodeUnauthenticated  = "unauthenticated"
+	errCodeForbidden        = "forbidden"
+	errCodeNotFound         = "not_found"
+	errCodeRateLimited      = "rate_limited"
+	errCodeRedisRead        = "redis_read_error"
+	errCodeRedisWrite       = "redis_write_error"
+	errCodeRedisTimeout     = "redis_timeout"
+	errCodeUnavailable      = "unavailable"
+	errCodeEncryptionPolicy = "encryption_policy"
+	errCodeConversion       = "conversion_error"
+	errCodeInternal         = "internal_error"
+)
+
+type errorCode struct {
+	status    int
+	retryable bool
+	text      string // plain text body, the status text if empty
+}
+
+// errorCodes holds the status of each error code, documented in README.md
+var errorCodes = map[string]errorCode{
+	errCodeBadRequest:       {status: http.StatusBadRequest},
+	errCodeInvalidKeyspace:  {status: http.StatusBadRequest, text: "Invalid keyspace"},
+	errCodeUnsupportedMedia: {status: http.StatusUnsupportedMediaType},
+	errCodeNotAcceptable:    {status: http.StatusNotAcceptable},
+	errCodeInvalidContent:   {status: http.StatusBadRequest},
+	errCodeSchemaViolation:  {status: http.StatusBadRequest, text: "Bad Request: schema violation"},
+	errCodeTooLarge:         {status: http.StatusRequestEntityTooLarge},
+	errCodeUnauthenticated:  {status: http.StatusUnauthorized},
+	errCodeForbidden:        {status: http.StatusForbidden},
+	errCodeNotFound:         {status: http.StatusNotFound},
+	errCodeRateLimited:      {status: http.StatusTooManyRequests, retryable: true},
+	errCodeRedisRead:        {status: http.StatusInternalServerError, retryable: true, text: "Internal Server Error 10"},
+	errCodeRedisWrite:       {status: http.StatusInternalServerError, retryable: true, text: "Internal Server Error 11"},
+	errCodeRedisTimeout:     {status: http.StatusGatewayTimeout, retryable: true},
+	errCodeUnavailable:      {status: http.StatusServiceUnavailable, retryable: true},
+	errCodeEncryptionPolicy: {status: http.StatusInternalServerError, text: "Internal Server Error 12"},
+	errCodeConversion:       {status: http.StatusInternalServerError},
+	errCodeInternal:         {status: http.StatusInternalServerError},
+}
+
+// apiError is the JSON error body
+type apiError struct {
+	Code      string   `json:"code"`
+	Message   string   `json:"message"`
+	Keyspace  string   `json:"keyspace,omitempty"`
+	RequestID string   `json:"requestId,omitempty"`
+	Retryable bool     `json:"retryable"`
+	Details   []string `json:"details,omitempty"`
+}
+
+// sendError sends the error of code. Requests that accept application/json get an apiError,
+// others the plain text body of the code followed by details, one per line.
+func (s *Server) sendError(w http.ResponseWriter, r *http.Request, code, message string, details ...string) {
+	c := errorCodes[code]
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if !acceptsJSON(r) {
+		text := c.text
+		if text == "" {
+			text = http.StatusText(c.status)
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(c.status)
+		w.Write([]byte(strings.Join(append([]string{text}, details...), "\n") + "\n"))
+		return
+	}
+	result := struct {
+		Error apiError `json:"error"`
+	}{apiError{
+		Code:      code,
+		Message:   message,
+		Keyspace:  chi.URLParam(r, "keyspace"),
+		RequestID: requestid.FromContext(r.Context()),
+		Retryable: c.retryable,
+		Details:   details,
+	}}
+	output, _ := json.Marshal(result)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(c.status)
+	w.Write(output)
+}
+
+// acceptsJSON reports whether the Accept header of r lists application/json
+func acceptsJSON(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaType := range strings.Split(accept, ",") {
+			mediaType, _, _ = strings.Cut(mediaType, ";")
+			if strings.EqualFold(strings.TrimSpace(mediaType), "application/json") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sendConversionErr sends the error of converting the request body, or the stored data for GET.
+// Limits and unknown formats are mapped to 413, 415 or 406, other errors to defaultCode.
+func (s *Server) sendConversionErr(w http.ResponseWriter, r *http.Request, err error, defaultCode string) {
+	var sizeError *conversion.SizeError
+	switch {
+	case errors.As(err, &sizeError):
+		if r.Method != http.MethodGet {
+			s.metrics.tooLarge.WithLabelValues(chi.URLParam(r, "keyspace"), "decompressed").Inc()
+			s.sendError(w, r, errCodeTooLarge, err.Error())
+			return
+		}
+	case errors.Is(err, conversion.ErrCorrupt):
+		if r.Method != http.MethodGet {
+			s.sendError(w, r, errCodeInvalidContent, err.Error())
+			return
+		}
+	case errors.Is(err, conversion.ErrUnknownContentType), errors.Is(err, conversion.ErrUnknownEncodingType),
+		errors.Is(err, conversion.ErrUnknownKeyspace):
+		code := errCodeUnsupportedMedia
+		if r.Method == http.MethodGet {
+			code = errCodeNotAcceptable
+		}
+		s.sendError(w, r, code, err.Error())
+		return
+	}
+	s.sendError(w, r, defaultCode, err.Error())
+}
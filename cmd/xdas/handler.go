@@ -18,20 +18,27 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"mime/multipart"
+	"net"
 	"net/http"
 	"net/textproto"
 	"strconv"
 	"strings"
+	"time"
+	"xdas/internal/auth"
+	"xdas/internal/cache"
 	"xdas/internal/conversion"
 	"xdas/internal/keyspaces"
 	"xdas/internal/magicbyte"
+	"xdas/internal/notify"
+	"xdas/internal/requestid"
+	"xdas/internal/store"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-redis/redis/v7"
 )
 
 func (s *Server) handleFuncXdasGet(w http.ResponseWriter, r *http.Request) {
@@ -42,57 +49,93 @@ func (s *Server) handleFuncXdasGet(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) xdasCommonGet(keyspace, id, key string, w http.ResponseWriter, r *http.Request) {
-	ksConf, ok := s.config.Keyspaces[keyspace]
+	ksConf, ok := s.cfg().Keyspaces[keyspace]
 	if !ok { // should not happen, handled by validateKeyspace
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		s.sendError(w, r, errCodeInvalidKeyspace, "unknown keyspace")
 		return
 	}
 
+	ctx, cancel := redisContext(r, ksConf)
+	defer cancel()
 	if ksConf.Kind == keyspaces.KSAtomic { // Atomic keyspaces are native Redis string type
-		s.handleFuncXdasAtomicGet(key, w, r)
+		s.handleFuncXdasAtomicGet(ctx, keyspace, key, w, r)
 		return
 	}
 
-	magicByte, data, err := redisGet(s.redis, key)
+	outFormat := r.URL.Query().Get("format")
+	outMagicByte := outputMagicByte(ksConf, outFormat)
+	cached := ksConf.Cache.Enabled && outFormat != "raw" && (outFormat == "" || outMagicByte.GetCTV() != 0)
+	gen := ksConf.Cache.Gen() // before reading the store, so a concurrent write isn't undone by Add
+	if cached {
+		if v, ok := ksConf.Cache.Get(key, outMagicByte); ok {
+			s.metrics.l1Hits.WithLabelValues(keyspace).Inc()
+			s.metrics.cacheHits.WithLabelValues(keyspace).Inc()
+			writeValue(w, v.MagicByte, v.Data)
+			return
+		}
+		s.metrics.l1Misses.WithLabelValues(keyspace).Inc()
+	}
+
+	magicByte, data, err := storeGet(ctx, s.store, key)
 	if err != nil {
-		if err == redis.Nil {
+		if err == store.ErrNotFound {
+			var findXTriggered bool
 			if !parseBool("nofindx", r.URL.Query()) {
-				findX(s.redis, ksConf, keyspace, id)
+				findXTriggered = findX(ctx, s.store, ksConf, keyspace, id)
 			}
-			w.WriteHeader(http.StatusNotFound)
-			fmt.Fprintln(w, http.StatusText(http.StatusNotFound))
+			s.metrics.cacheMiss(keyspace, findXTriggered)
+			s.sendError(w, r, errCodeNotFound, "key not found")
 			return
 		}
-		s.sendRedisReadErr(w, err)
+		s.metrics.cacheErrors.WithLabelValues(keyspace).Inc()
+		s.sendRedisReadErr(w, r, err)
 
 		return
 	}
+	s.metrics.cacheHits.WithLabelValues(keyspace).Inc()
+	if !s.allowRead(r, ksConf, keyspace, key, magicByte) {
+		s.sendEncryptionPolicyErr(w, r)
+		return
+	}
 
-	var outMagicByte magicbyte.MagicByte
-	switch outFormat := r.URL.Query().Get("format"); outFormat {
+	switch outFormat {
 	case "":
-		outMagicByte = ksConf.Output.magicByte
 	case "raw":
 		w.Header().Set("Content-type", "application/octet-stream")
 		w.Write([]byte{magicByte.Get()})
 		w.Write(data)
 		return
 	default:
-		outMagicByte = magicbyte.New("", outFormat, 0)
 		if outMagicByte.GetCTV() == 0 {
-			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			s.sendError(w, r, errCodeNotAcceptable, "unknown format "+outFormat)
 			return
 		}
 	}
 
-	magicByte, data, err = conversion.Convert(keyspace, magicByte, outMagicByte, data)
+	magicByte, data, err = conversion.ConvertContext(r.Context(), keyspace, magicByte, outMagicByte, data)
 	if err != nil {
-		s.log.Error("Conversion error", "keyspace", keyspace, "key", key, "err", err)
+		s.reqLog(r).Error("Conversion error", "keyspace", keyspace, "key", key, "err", err)
 		// return error or original content?
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		s.sendConversionErr(w, r, err, errCodeConversion)
 		return
 	}
+	if cached {
+		ksConf.Cache.Add(key, outMagicByte, gen, cache.Value{MagicByte: magicByte, Data: data})
+	}
+	writeValue(w, magicByte, data)
+}
+
+// outputMagicByte returns the magicByte of the format query parameter, the keyspace Output if it's
+// empty. Its CTV is 0 if the format is unknown.
+func outputMagicByte(ksConf *KeyspaceConfig, format string) magicbyte.MagicByte {
+	if format == "" {
+		return ksConf.Output.magicByte
+	}
+	return magicbyte.New("", format, 0)
+}
 
+// writeValue writes data with the content headers of magicByte
+func writeValue(w http.ResponseWriter, magicByte magicbyte.MagicByte, data []byte) {
 	magicByte.SetContentHeaders(w.Header())
 	w.Header().Set("Content-length", strconv.Itoa(len(data)))
 	w.Write(data)
@@ -120,10 +163,10 @@ func (s *Server) xdasCommonGet(keyspace, id, key string, w http.ResponseWriter,
 
 func (s *Server) handleFuncXdasPut(w http.ResponseWriter, r *http.Request) {
 	keyspace := chi.URLParam(r, "keyspace")
-	ksConf, ok := s.config.Keyspaces[keyspace]
+	ksConf, ok := s.cfg().Keyspaces[keyspace]
 	if !ok {
 		// s.Println("Invalid keyspace", keyspace)
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		s.sendError(w, r, errCodeInvalidKeyspace, "unknown keyspace")
 		return
 	}
 	id := getID(r)
@@ -135,8 +178,9 @@ func (s *Server) handleFuncXdasPut(w http.ResponseWriter, r *http.Request) {
 	// validate content-type and content-encoding against config
 	if inputMagicByte.GetCEV() != 0 && inputMagicByte.GetCEV() != magicByte.GetCEV() ||
 		inputMagicByte.GetCTV() != 0 && inputMagicByte.GetCTV() != magicByte.GetCTV() {
-		s.log.Info("Invalid content format", "keyspace", keyspace, "id", id, "mb", magicByte)
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		s.reqLog(r).Info("Invalid content format", "keyspace", keyspace, "id", id, "mb", magicByte)
+		s.sendError(w, r, errCodeUnsupportedMedia, fmt.Sprintf("keyspace input is contentType %q, contentEncoding %q",
+			ksConf.Input.ContentType, ksConf.Input.ContentEncoding))
 		return
 	}
 
@@ -145,15 +189,27 @@ func (s *Server) handleFuncXdasPut(w http.ResponseWriter, r *http.Request) {
 	b1.Reset()
 	data, err := readAll(r.Body, b1)
 	if err != nil {
-		s.sendRequestBodyReadErr(w, err)
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			s.metrics.tooLarge.WithLabelValues(keyspace, "request").Inc()
+		}
+		s.sendRequestBodyReadErr(w, r, err)
 		return
 	}
 
-	if s.config.ValidateContent {
-		_, err := conversion.Unpack(keyspace, magicByte, data)
+	if s.cfg().ValidateContent {
+		_, err := conversion.UnpackContext(r.Context(), keyspace, magicByte, data)
 		if err != nil {
-			s.log.Info("Invalid request", "keyspace", keyspace, "id", id, "err", err, "data", data)
-			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			s.reqLog(r).Info("Invalid request", "keyspace", keyspace, "id", id, "err", err, "data", data)
+			s.sendConversionErr(w, r, err, errCodeInvalidContent)
+			return
+		}
+	}
+
+	if ksConf.schema != nil {
+		if err := validateSchema(ksConf.schema, keyspace, magicByte, data); err != nil {
+			s.reqLog(r).Info("Schema validation failed", "keyspace", keyspace, "id", id, "err", err)
+			s.sendSchemaErr(w, r, err)
 			return
 		}
 	}
@@ -162,17 +218,16 @@ func (s *Server) handleFuncXdasPut(w http.ResponseWriter, r *http.Request) {
 
 	// may remove this validation in the future
 	if storeMagicByte.GetCEV() == 0 && magicByte.GetCEV() != 0 {
-		_, err := conversion.Unpack(keyspace, magicByte, data)
+		_, err := conversion.UnpackContext(r.Context(), keyspace, magicByte, data)
 		if err != nil {
-			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			s.sendConversionErr(w, r, err, errCodeInvalidContent)
 			return
 		}
 	}
-	magicByte, data, err = conversion.Convert(keyspace, magicByte, storeMagicByte, data)
+	magicByte, data, err = conversion.ConvertContext(r.Context(), keyspace, magicByte, storeMagicByte, data)
 	if err != nil {
-		s.log.Error("Conversion error", "keyspace", keyspace, "key", key, "err", err)
-		// Most likely bad content or encoding, should return BadRequest in the future
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		s.reqLog(r).Error("Conversion error", "keyspace", keyspace, "key", key, "err", err)
+		s.sendConversionErr(w, r, err, errCodeInvalidContent)
 		return
 	}
 
@@ -181,19 +236,23 @@ func (s *Server) handleFuncXdasPut(w http.ResponseWriter, r *http.Request) {
 	b2 := writeToBufPool(&s.bufPool, magicByte.Get(), data)
 	defer s.bufPool.Put(b2)
 
-	result, err := s.redis.Set(key, b2.Bytes(), ttl).Result()
-	if err != nil { // set MaxRetries under Redis:ClientConfig in config to retry
-		s.sendRedisWriteErr(w, err)
+	ctx, cancel := redisContext(r, ksConf)
+	defer cancel()
+	if _, err := s.store.Set(ctx, key, b2.Bytes(), ttl, store.Always); err != nil {
+		// set MaxRetries under Redis:ClientConfig in config to retry
+		s.sendRedisWriteErr(w, r, err)
 		return
 	}
-	fmt.Fprintln(w, result)
+	s.metrics.storedSize.WithLabelValues(keyspace).Observe(float64(b2.Len()))
+	s.onChange(r, ksConf, keyspace, notify.OpPut, id, ttl, magicByte, data)
+	fmt.Fprintln(w, "OK")
 }
 
 func (s *Server) handleFuncXdasMultiGet(w http.ResponseWriter, r *http.Request) {
 	id := getID(r)
 	var reqKeyspaces []string
 	if ks := r.URL.Query().Get("ks"); ks == "" {
-		reqKeyspaces = s.config.Multipart.Keyspaces
+		reqKeyspaces = s.cfg().Multipart.Keyspaces
 	} else {
 		reqKeyspaces = strings.Split(ks, ",")
 	}
@@ -202,9 +261,13 @@ func (s *Server) handleFuncXdasMultiGet(w http.ResponseWriter, r *http.Request)
 	ksConfs := make([]*KeyspaceConfig, len(reqKeyspaces))
 	var validKeyspaceCount int
 	for _, reqKeyspace := range reqKeyspaces {
-		ksConf, ok := s.config.Keyspaces[reqKeyspace]
+		ksConf, ok := s.cfg().Keyspaces[reqKeyspace]
 		if !ok {
-			s.log.Info("Invalid keyspace in multi", "keyspace", reqKeyspace, "ip", r.RemoteAddr, "url", r.RequestURI)
+			s.reqLog(r).Info("Invalid keyspace in multi", "keyspace", reqKeyspace, "ip", r.RemoteAddr, "url", r.RequestURI)
+			continue
+		}
+		if !s.allowed(r, reqKeyspace, auth.Read) {
+			s.reqLog(r).Info("Forbidden keyspace in multi", "keyspace", reqKeyspace, "principal", auth.FromContext(r.Context()))
 			continue
 		}
 		if reqKeyspace != "ct" {
@@ -222,14 +285,18 @@ func (s *Server) handleFuncXdasMultiGet(w http.ResponseWriter, r *http.Request)
 	keyspaces = keyspaces[:validKeyspaceCount]
 	ksConfs = ksConfs[:validKeyspaceCount]
 	if len(keyspaces) < 1 {
-		w.WriteHeader(http.StatusNotFound)
-		fmt.Fprintln(w, http.StatusText(http.StatusNotFound))
+		s.sendError(w, r, errCodeNotFound, "no requested keyspace found")
 		return
 	}
 
-	results, err := s.redis.MGet(keys...).Result()
+	ctx, cancel := redisContext(r, ksConfs...)
+	defer cancel()
+	results, err := s.store.MGet(ctx, keys...)
 	if err != nil {
-		s.sendRedisReadErr(w, err)
+		for _, keyspace := range keyspaces {
+			s.metrics.cacheErrors.WithLabelValues(keyspace).Inc()
+		}
+		s.sendRedisReadErr(w, r, err)
 		return
 	}
 
@@ -240,50 +307,57 @@ func (s *Server) handleFuncXdasMultiGet(w http.ResponseWriter, r *http.Request)
 	mw := multipart.NewWriter(w)
 	w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
 	var validResultCount int
-	for index, result := range results {
-		if result == nil {
+	for index, value := range results {
+		if value == nil {
+			var findXTriggered bool
 			if !parseBool("nofindx", r.URL.Query()) {
-				findX(s.redis, ksConfs[index], keyspaces[index], id)
+				findXTriggered = findX(ctx, s.store, ksConfs[index], keyspaces[index], id)
 			}
+			s.metrics.cacheMiss(keyspaces[index], findXTriggered)
+			continue
+		}
+		if len(value) < magicbyte.MagicByteLength {
+			s.reqLog(r).Error("Invalid value in multi", "keyspace", keyspaces[index], "key", keys[index])
+			s.metrics.cacheErrors.WithLabelValues(keyspaces[index]).Inc()
 			continue
 		}
-		r, ok := result.(string)
-		if !ok || len(r) < magicbyte.MagicByteLength {
+		s.metrics.cacheHits.WithLabelValues(keyspaces[index]).Inc()
+		magicByte := magicbyte.NewFrom(value[0])
+		if !s.allowRead(r, ksConfs[index], keyspaces[index], keys[index], magicByte) {
 			continue
 		}
-		magicByte := magicbyte.NewFrom(r[0])
-		data := []byte(r[magicbyte.MagicByteLength:])
+		data := value[magicbyte.MagicByteLength:]
 
 		outMagicByte := ksConfs[index].Output.magicByte
-		magicByte, data, err = conversion.Convert(keyspaces[index], magicByte, outMagicByte, data)
+		magicByte, data, err = conversion.ConvertContext(r.Context(), keyspaces[index], magicByte, outMagicByte, data)
 		if err != nil {
-			s.log.Error("Data conversion error:", "keyspace", keyspaces[index], "key", keys[index], "err", err)
+			s.reqLog(r).Error("Data conversion error:", "keyspace", keyspaces[index], "key", keys[index], "err", err)
 			continue
 		}
 
 		h := make(textproto.MIMEHeader)
 		magicByte.SetContentHeaders(h)
+		h.Set(requestid.Header, requestid.FromContext(r.Context()))
 		if ks := keyspaces[index]; ks != "ct" {
 			h.Set("Namespace", keyspaces[index])
 		} else {
 			keyParts := strings.Split(keys[index], "_")
 			if len(keyParts) != 3 {
-				s.log.Error("Invalid ct key", "key", keys[index])
+				s.reqLog(r).Error("Invalid ct key", "key", keys[index])
 				continue
 			}
 			h.Set("Namespace", ks+"_"+keyParts[1]+"_"+keyParts[2])
 		}
 		part, err := mw.CreatePart(h)
 		if err != nil {
-			s.log.Error("Multipart creation error:", "key", keys[index], "err", err)
+			s.reqLog(r).Error("Multipart creation error:", "key", keys[index], "err", err)
 			continue
 		}
 		part.Write(data)
 		validResultCount++
 	}
 	if validResultCount == 0 {
-		w.WriteHeader(http.StatusNotFound)
-		fmt.Fprintln(w, http.StatusText(http.StatusNotFound))
+		s.sendError(w, r, errCodeNotFound, "key not found in any requested keyspace")
 		return
 	}
 	mw.Close()
@@ -300,49 +374,102 @@ func (s *Server) handleFuncXdasDel(w http.ResponseWriter, r *http.Request) {
 	id := getID(r)
 	key := redisKey(keyspace, id)
 
-	result, err := s.redis.Del(key).Result()
+	ksConf, ok := s.cfg().Keyspaces[keyspace]
+	if !ok { // should not happen, handled by validateKeyspace
+		s.sendError(w, r, errCodeInvalidKeyspace, "unknown keyspace")
+		return
+	}
+	ctx, cancel := redisContext(r, ksConf)
+	defer cancel()
+	result, err := s.store.Del(ctx, key)
 	if err != nil {
-		s.sendRedisWriteErr(w, err)
+		s.sendRedisWriteErr(w, r, err)
 		return
 	}
 	if result < 1 {
-		w.WriteHeader(http.StatusNotFound)
-		fmt.Fprintln(w, http.StatusText(http.StatusNotFound))
+		s.sendError(w, r, errCodeNotFound, "key not found")
 		return
 	}
+	s.onChange(r, ksConf, keyspace, notify.OpDel, id, 0, magicbyte.MagicByte{}, nil)
 	fmt.Fprintln(w, result)
 }
 
-func (s *Server) sendRequestBodyReadErr(w http.ResponseWriter, err error) {
-	s.log.Info("Error reading body", "err", err)
+// notifyChange queues a change notification if the keyspace is configured for op.
+// data is converted to the Output format and copied, as its buffer is reused once
+// the handler returns.
+func (s *Server) notifyChange(ctx context.Context, r *http.Request, ksConf *KeyspaceConfig, keyspace, op, id string, ttl time.Duration,
+	magicByte magicbyte.MagicByte, data []byte) {
+	if !ksConf.Notify.Wants(op) {
+		return
+	}
+	e := notify.Event{Op: op, ID: id, TTL: ttl}
+	if ksConf.Notify.WantsBody(op) && len(data) > 0 {
+		outMagicByte, out, err := conversion.ConvertContext(ctx, keyspace, magicByte, ksConf.Output.magicByte, data)
+		if err != nil {
+			s.reqLog(r).Error("Notify conversion error", "keyspace", keyspace, "id", id, "err", err)
+		} else {
+			e.Body = append([]byte(nil), out...)
+			e.ContentType = outMagicByte.GetContentType()
+			e.ContentEncoding = outMagicByte.GetContentEncoding()
+		}
+	}
+	ksConf.Notify.Add(e)
+}
+
+func (s *Server) sendRequestBodyReadErr(w http.ResponseWriter, r *http.Request, err error) {
+	s.reqLog(r).Info("Error reading body", "err", err)
 
 	var maxBytesError *http.MaxBytesError
 	if errors.As(err, &maxBytesError) {
-		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		s.sendError(w, r, errCodeTooLarge, "request body exceeds limit of "+strconv.FormatInt(maxBytesError.Limit, 10)+" bytes")
 		return
 	}
 
-	http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	s.sendError(w, r, errCodeBadRequest, "error reading request body")
 }
 
-func (s *Server) sendRedisReadErr(w http.ResponseWriter, err error) {
-	s.log.Error("Redis read error", "err", err)
+func (s *Server) sendRedisReadErr(w http.ResponseWriter, r *http.Request, err error) {
+	if s.sendRedisCanceled(w, r, err) {
+		return
+	}
+	s.reqLog(r).Error("Redis read error", "err", err)
 	s.metrics.redisReadErr.Inc()
-	http.Error(w, "Internal Server Error 10", http.StatusInternalServerError)
+	if isTimeout(err) {
+		s.sendError(w, r, errCodeRedisTimeout, "timeout reading from Redis")
+		return
+	}
+	s.sendError(w, r, errCodeRedisRead, "error reading from Redis")
 }
 
-func (s *Server) sendRedisWriteErr(w http.ResponseWriter, err error) {
-	s.log.Error("Redis write error", "err", err)
+func (s *Server) sendRedisWriteErr(w http.ResponseWriter, r *http.Request, err error) {
+	if s.sendRedisCanceled(w, r, err) {
+		return
+	}
+	s.reqLog(r).Error("Redis write error", "err", err)
 	s.metrics.redisWriteErr.Inc()
-	http.Error(w, "Internal Server Error 11", http.StatusInternalServerError)
+	if isTimeout(err) {
+		s.sendError(w, r, errCodeRedisTimeout, "timeout writing to Redis")
+		return
+	}
+	s.sendError(w, r, errCodeRedisWrite, "error writing to Redis")
 }
 
-// By request to print raw config, not a good idea as it contains password
-// func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
-// 	w.Header().Set("Content-type", "application/json")
-// 	w.WriteHeader(http.StatusOK)
-// 	w.Write(s.config.raw)
-// }
+// sendRedisCanceled returns true if the Redis call failed because the client went away, which is
+// not counted as a Redis error
+func (s *Server) sendRedisCanceled(w http.ResponseWriter, r *http.Request, err error) bool {
+	if !errors.Is(err, context.Canceled) || r.Context().Err() == nil {
+		return false
+	}
+	s.reqLog(r).Debug("Redis call canceled by the client", "err", err)
+	s.sendError(w, r, errCodeRedisTimeout, "request canceled")
+	return true
+}
+
+// isTimeout returns true if err is a timeout, of the context or of the connection to Redis
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
 
 func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
 	version := struct {
@@ -365,27 +492,29 @@ func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
 	w.Write(output)
 }
 
-func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintln(w, http.StatusText(http.StatusOK))
-}
-
 // getID returns the URL parameter value of "id"
 func getID(r *http.Request) string {
 	return strings.ToUpper(chi.URLParam(r, "id"))
 }
 
-func findX(rdb redis.UniversalClient, ksConf *KeyspaceConfig, keyspace, id string) {
+// findX looks up id through FindX, if enabled for the keyspace. It returns whether a lookup was
+// triggered, for pld it may still be rejected if id doesn't exist in pa.
+func findX(ctx context.Context, st store.Store, ksConf *KeyspaceConfig, keyspace, id string) bool {
+	if !ksConf.FindX.Enabled {
+		return false
+	}
+	ctx = context.WithoutCancel(ctx) // the lookup outlives the request
 	switch keyspace {
 	case "pld":
 		go func() {
-			if rdb.Exists(redisKey("pa", id)).Val() == 0 { // Only trigger findX if id exist in pa keyspace
+			if ok, _ := st.Exists(ctx, redisKey("pa", id)); !ok { // Only trigger findX if id exist in pa keyspace
 				ksConf.FindX.Reject()
 				return
 			}
-			ksConf.FindX.Add(id)
+			ksConf.FindX.AddContext(ctx, id)
 		}()
 	default:
-		ksConf.FindX.Add(id)
+		ksConf.FindX.AddContext(ctx, id)
 	}
+	return true
 }
@@ -17,27 +17,32 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strconv"
 	"time"
+	"xdas/internal/magicbyte"
+	"xdas/internal/notify"
+	"xdas/internal/store"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-redis/redis/v7"
 )
 
 // handleFuncXdasAtomicGet returns the value of an atomic keyspace
-func (s *Server) handleFuncXdasAtomicGet(key string, w http.ResponseWriter, r *http.Request) {
-	result, err := s.redis.Get(key).Bytes()
+func (s *Server) handleFuncXdasAtomicGet(ctx context.Context, keyspace, key string, w http.ResponseWriter, r *http.Request) {
+	result, err := s.store.Get(ctx, key)
 	if err != nil {
-		if err == redis.Nil {
-			w.WriteHeader(http.StatusNotFound)
-			fmt.Fprintln(w, http.StatusText(http.StatusNotFound))
+		if err == store.ErrNotFound {
+			s.metrics.cacheMiss(keyspace, false)
+			s.sendError(w, r, errCodeNotFound, "key not found")
 			return
 		}
-		s.sendRedisReadErr(w, err)
+		s.metrics.cacheErrors.WithLabelValues(keyspace).Inc()
+		s.sendRedisReadErr(w, r, err)
 		return
 	}
+	s.metrics.cacheHits.WithLabelValues(keyspace).Inc()
 	// w.Header().Set("Content-type", "application/octet-stream")
 	w.Write(result)
 }
@@ -47,9 +52,9 @@ func (s *Server) handleFuncXdasAtomicInc(w http.ResponseWriter, r *http.Request)
 	keyspace := chi.URLParam(r, "keyspace")
 	id := getID(r)
 	key := redisKey(keyspace, id)
-	ksConf, ok := s.config.Keyspaces[keyspace]
+	ksConf, ok := s.cfg().Keyspaces[keyspace]
 	if !ok { // should not happen, already checked by validateAtomicKeyspace
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		s.sendError(w, r, errCodeInvalidKeyspace, "unknown keyspace")
 		return
 	}
 	ttl := getTTL(r.Header.Get("Xttl"), ksConf.ttl)
@@ -58,17 +63,19 @@ func (s *Server) handleFuncXdasAtomicInc(w http.ResponseWriter, r *http.Request)
 		n = 1
 	}
 
-	s.atomicIncrBy(key, n, ttl, w)
+	s.atomicIncrBy(ksConf, keyspace, id, key, n, ttl, w, r)
 }
 
-func (s *Server) atomicIncrBy(key string, n int64, ttl time.Duration, w http.ResponseWriter) {
-	pipe := s.redis.Pipeline()
-	result := pipe.IncrBy(key, n)
-	pipe.Expire(key, ttl)
-	_, err := pipe.Exec()
+func (s *Server) atomicIncrBy(ksConf *KeyspaceConfig, keyspace, id, key string, n int64, ttl time.Duration,
+	w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := redisContext(r, ksConf)
+	defer cancel()
+	result, err := s.store.IncrBy(ctx, key, n, ttl)
 	if err != nil {
-		s.sendRedisWriteErr(w, err)
+		s.sendRedisWriteErr(w, r, err)
 		return
 	}
-	fmt.Fprint(w, result.Val())
+	s.onChange(r, ksConf, keyspace, notify.OpInc, id, ttl, magicbyte.MagicByte{},
+		strconv.AppendInt(nil, result, 10))
+	fmt.Fprint(w, result)
 }
@@ -0,0 +1,170 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultDrainDelay  = 5 * time.Second
+	defaultPingTimeout = time.Second
+
+	statusOK          = "ok"
+	statusUnavailable = "unavailable"
+	statusDraining    = "draining"
+	statusFailed      = "failed"
+	statusReloading   = "reloading"
+)
+
+// readiness is the status document of /readyz
+type readiness struct {
+	Status string                 `json:"status"`
+	Redis  *redisReadiness        `json:"redis,omitempty"` // nil with the memory store
+	FindX  map[string]findXStatus `json:"findx"`
+	Reload reloadStatus           `json:"reload"`
+}
+
+type redisReadiness struct {
+	Status string            `json:"status"`
+	Nodes  map[string]string `json:"nodes"` // status of each node by address
+}
+
+type findXStatus struct {
+	Status  string `json:"status"`
+	Running int    `json:"running"`
+	Started int    `json:"started"`
+}
+
+type reloadStatus struct {
+	Status string     `json:"status"`
+	Time   *time.Time `json:"time,omitempty"`
+	Error  string     `json:"error,omitempty"`
+}
+
+// handleHealthz is the liveness check, it is OK as long as the server is running
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, http.StatusText(http.StatusOK))
+}
+
+// handleReadyz is the readiness check. It returns 503 if any Redis node doesn't answer a ping,
+// if a FindX worker has exited, or while draining on shutdown. A failed config reload is
+// reported but keeps the server ready, as it still serves the previous config.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	var result struct {
+		Data readiness `json:"data"`
+	}
+	ready := &result.Data
+	ready.Status = statusOK
+	if s.redis != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), s.cfg().Health.pingTimeout)
+		ready.Redis = s.pingRedis(ctx)
+		cancel()
+	}
+	ready.FindX = make(map[string]findXStatus)
+	for keyspace, ksConf := range s.cfg().Keyspaces {
+		if !ksConf.FindX.Enabled {
+			continue
+		}
+		running, started := ksConf.FindX.Workers()
+		status := findXStatus{Status: statusOK, Running: running, Started: started}
+		if started == 0 || running < started {
+			status.Status = statusUnavailable
+			ready.Status = statusUnavailable
+		}
+		ready.FindX[keyspace] = status
+	}
+	ready.Reload = s.reloadStatus()
+	if ready.Redis != nil && ready.Redis.Status != statusOK {
+		ready.Status = statusUnavailable
+	}
+	if s.draining.Load() {
+		ready.Status = statusDraining
+	}
+
+	code := http.StatusOK
+	if ready.Status != statusOK {
+		code = http.StatusServiceUnavailable
+	}
+	output, _ := json.Marshal(result)
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(code)
+	w.Write(output)
+}
+
+// pingRedis pings every node of the cluster, or the server of other topologies
+func (s *Server) pingRedis(ctx context.Context) *redisReadiness {
+	ready := &redisReadiness{Status: statusOK, Nodes: make(map[string]string)}
+	var mu sync.Mutex
+	ping := func(ctx context.Context, c *redis.Client) error {
+		err := c.Ping(ctx).Err()
+		status := statusOK
+		if err != nil {
+			status = err.Error()
+		}
+		mu.Lock()
+		ready.Nodes[c.Options().Addr] = status
+		mu.Unlock()
+		return err
+	}
+
+	var err error
+	switch c := s.redis.(type) {
+	case *redis.ClusterClient:
+		err = c.ForEachShard(ctx, ping)
+		if err != nil && len(ready.Nodes) == 0 { // no node known
+			ready.Nodes["cluster"] = err.Error()
+		}
+	case *redis.Client:
+		err = ping(ctx, c)
+	default:
+		err = s.redis.Ping(ctx).Err()
+		if err != nil {
+			ready.Nodes["default"] = err.Error()
+		}
+	}
+	if err != nil {
+		ready.Status = statusUnavailable
+	}
+	return ready
+}
+
+// reloadStatus returns the outcome of the last config reload, reloading if one is in progress
+func (s *Server) reloadStatus() reloadStatus {
+	if !s.reload.mu.TryLock() {
+		return reloadStatus{Status: statusReloading}
+	}
+	defer s.reload.mu.Unlock()
+	status := reloadStatus{Status: statusOK}
+	if !s.reload.time.IsZero() {
+		t := s.reload.time
+		status.Time = &t
+	}
+	if s.reload.err != nil {
+		status.Status = statusFailed
+		status.Error = s.reload.err.Error()
+	}
+	return status
+}
@@ -19,20 +19,39 @@ package main
 import (
 	"net/http"
 	"runtime"
+	"strconv"
 	"strings"
+	"xdas/internal/auth"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
 )
 
 type appMetrics struct {
-	counter  *prometheus.CounterVec
-	duration *prometheus.HistogramVec
-	// responseSize  *prometheus.HistogramVec
-	// requestSize   *prometheus.HistogramVec
-	redisReadErr  prometheus.Counter
-	redisWriteErr prometheus.Counter
+	counter         *prometheus.CounterVec
+	duration        *prometheus.HistogramVec
+	ksDuration      *prometheus.HistogramVec
+	responseSize    *prometheus.HistogramVec
+	requestSize     *prometheus.HistogramVec
+	storedSize      *prometheus.HistogramVec
+	redisReadErr    prometheus.Counter
+	redisWriteErr   prometheus.Counter
+	redisChangesErr prometheus.Counter
+	plaintextRead   *prometheus.CounterVec
+	throttled       *prometheus.CounterVec
+	tooLarge        *prometheus.CounterVec
+	schemaInvalid   *prometheus.CounterVec
+	redisRateErr    prometheus.Counter
+	cacheHits       *prometheus.CounterVec
+	cacheMisses     *prometheus.CounterVec
+	cacheErrors     *prometheus.CounterVec
+	l1Hits          *prometheus.CounterVec
+	l1Misses        *prometheus.CounterVec
+	redisInvalErr   prometheus.Counter
+	changesPolls    prometheus.Gauge
+	watchers        prometheus.Gauge
 }
 
 func newMetrics() *appMetrics {
@@ -42,7 +61,7 @@ func newMetrics() *appMetrics {
 				Name: "api_requests_total",
 				Help: "A counter for total number of requests.",
 			},
-			[]string{"app", "code", "method", "keyspace", "client"}, // app name, status code, http method, request URL
+			[]string{"app", "code", "method", "keyspace", "client", "principal"}, // app name, status code, http method, request URL
 		),
 		duration: prometheus.NewHistogramVec(
 			prometheus.HistogramOpts{
@@ -52,22 +71,40 @@ func newMetrics() *appMetrics {
 			},
 			[]string{"app"},
 		),
-		// requestSize: prometheus.NewHistogramVec(
-		// 	prometheus.HistogramOpts{
-		// 		Name:    "api_request_size_bytes",
-		// 		Help:    "A histogram of request sizes for requests.",
-		// 		Buckets: []float64{200, 500, 1000, 10000, 100000},
-		// 	},
-		// 	[]string{"app", "keyspace"},
-		// ),
-		// responseSize: prometheus.NewHistogramVec(
-		// 	prometheus.HistogramOpts{
-		// 		Name:    "api_response_size_bytes",
-		// 		Help:    "A histogram of response sizes for requests.",
-		// 		Buckets: []float64{200, 500, 1000, 10000, 100000},
-		// 	},
-		// 	[]string{"app", "keyspace"},
-		// ),
+		ksDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: AppName,
+				Name:      "request_duration_seconds",
+				Help:      "A histogram of latencies for requests by keyspace.",
+				Buckets:   []float64{.001, .01, .03, 0.1, 0.5, 1, 3, 10, 130},
+			},
+			[]string{"keyspace", "method"},
+		),
+		requestSize: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "api_request_size_bytes",
+				Help:    "A histogram of request sizes for requests.",
+				Buckets: []float64{200, 500, 1000, 10000, 100000, 1000000},
+			},
+			[]string{"app", "keyspace", "method"},
+		),
+		responseSize: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "api_response_size_bytes",
+				Help:    "A histogram of response sizes for requests.",
+				Buckets: []float64{200, 500, 1000, 10000, 100000, 1000000},
+			},
+			[]string{"app", "keyspace", "method"},
+		),
+		storedSize: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: AppName,
+				Name:      "stored_value_size_bytes",
+				Help:      "A histogram of sizes of values written to Redis, in the store format.",
+				Buckets:   []float64{200, 500, 1000, 10000, 100000, 1000000},
+			},
+			[]string{"keyspace"},
+		),
 		redisReadErr: prometheus.NewCounter(
 			prometheus.CounterOpts{
 				Namespace:   AppName,
@@ -84,9 +121,122 @@ func newMetrics() *appMetrics {
 				ConstLabels: prometheus.Labels{"ops": "write"},
 			},
 		),
+		redisChangesErr: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace:   AppName,
+				Name:        "redis_errors_total",
+				Help:        "A counter of Redis errors.",
+				ConstLabels: prometheus.Labels{"ops": "changes"},
+			},
+		),
+		plaintextRead: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: AppName,
+				Name:      "plaintext_reads_total",
+				Help:      "A counter of plaintext records read from keyspaces that require encryption.",
+			},
+			[]string{"keyspace"},
+		),
+		throttled: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: AppName,
+				Name:      "ratelimit_throttled_total",
+				Help:      "A counter of requests rejected by rate limit.",
+			},
+			[]string{"keyspace", "client"},
+		),
+		tooLarge: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: AppName,
+				Name:      "payload_too_large_total",
+				Help:      "A counter of requests rejected for exceeding maxSize (stage request) or maxDecompressedSize (stage decompressed).",
+			},
+			[]string{"keyspace", "stage"},
+		),
+		schemaInvalid: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: AppName,
+				Name:      "schema_invalid_total",
+				Help:      "A counter of requests rejected for not validating against the keyspace JSON Schema.",
+			},
+			[]string{"keyspace"},
+		),
+		redisRateErr: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace:   AppName,
+				Name:        "redis_errors_total",
+				Help:        "A counter of Redis errors.",
+				ConstLabels: prometheus.Labels{"ops": "ratelimit"},
+			},
+		),
+		cacheHits: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: AppName,
+				Name:      "cache_hits_total",
+				Help:      "A counter of reads that found the key, for GET and each keyspace of multipart GET.",
+			},
+			[]string{"keyspace"},
+		),
+		cacheMisses: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: AppName,
+				Name:      "cache_misses_total",
+				Help:      "A counter of reads that didn't find the key, by whether FindX was triggered.",
+			},
+			[]string{"keyspace", "findx"},
+		),
+		cacheErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: AppName,
+				Name:      "cache_errors_total",
+				Help:      "A counter of reads that failed with a Redis error or an invalid stored value.",
+			},
+			[]string{"keyspace"},
+		),
+		l1Hits: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: AppName,
+				Name:      "l1_cache_hits_total",
+				Help:      "A counter of GET served from the in-process cache of the keyspace.",
+			},
+			[]string{"keyspace"},
+		),
+		l1Misses: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: AppName,
+				Name:      "l1_cache_misses_total",
+				Help:      "A counter of GET not found in the in-process cache of the keyspace.",
+			},
+			[]string{"keyspace"},
+		),
+		redisInvalErr: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace:   AppName,
+				Name:        "redis_errors_total",
+				Help:        "A counter of Redis errors.",
+				ConstLabels: prometheus.Labels{"ops": "invalidate"},
+			},
+		),
+		changesPolls: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: AppName,
+				Name:      "changes_polls",
+				Help:      "A gauge of the open long polls of the changes API.",
+			},
+		),
+		watchers: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: AppName,
+				Name:      "watchers",
+				Help:      "A gauge of the open watch streams.",
+			},
+		),
 	}
-	// prometheus.MustRegister(metrics.counter, metrics.duration, metrics.responseSize, metrics.requestSize,
-	prometheus.MustRegister(metrics.counter, metrics.duration, metrics.redisReadErr, metrics.redisWriteErr)
+	prometheus.MustRegister(metrics.counter, metrics.duration, metrics.ksDuration, metrics.responseSize, metrics.requestSize,
+		metrics.storedSize, metrics.redisReadErr, metrics.redisWriteErr, metrics.redisChangesErr,
+		metrics.plaintextRead, metrics.throttled, metrics.redisRateErr, metrics.tooLarge, metrics.schemaInvalid,
+		metrics.cacheHits, metrics.cacheMisses, metrics.cacheErrors, metrics.l1Hits, metrics.l1Misses,
+		metrics.redisInvalErr, metrics.changesPolls, metrics.watchers)
 	createBuildInfoMetrics()
 	return metrics
 }
@@ -107,19 +257,75 @@ func createBuildInfoMetrics() {
 func (m *appMetrics) appMetrics(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		keyspace := chi.URLParam(r, "keyspace")
-		const maxUA = 12
-		ua := strings.Split(r.UserAgent(), "/")[0]
-		if len(ua) > maxUA {
-			ua = ua[:maxUA]
-		}
+		ua := clientUA(r)
+
+		principal := auth.FromContext(r.Context()).MetricsLabel()
 
+		labels := prometheus.Labels{"app": AppName, "keyspace": keyspace}
 		promhttp.InstrumentHandlerDuration(m.duration.MustCurryWith(prometheus.Labels{"app": AppName}),
-			promhttp.InstrumentHandlerCounter(m.counter.MustCurryWith(prometheus.Labels{"app": AppName, "keyspace": keyspace, "client": ua}), next),
-			// promhttp.InstrumentHandlerCounter(m.counter.MustCurryWith(prometheus.Labels{"app": AppName, "keyspace": keyspace, "client": ua}),
-			// 	promhttp.InstrumentHandlerRequestSize(m.requestSize.MustCurryWith(prometheus.Labels{"app": AppName, "keyspace": keyspace}),
-			// 		promhttp.InstrumentHandlerResponseSize(m.responseSize.MustCurryWith(prometheus.Labels{"app": AppName, "keyspace": keyspace}), next),
-			// 	),
-			// ),
+			promhttp.InstrumentHandlerDuration(m.ksDuration.MustCurryWith(prometheus.Labels{"keyspace": keyspace}),
+				promhttp.InstrumentHandlerCounter(m.counter.MustCurryWith(prometheus.Labels{"app": AppName, "keyspace": keyspace, "client": ua, "principal": principal}),
+					promhttp.InstrumentHandlerRequestSize(m.requestSize.MustCurryWith(labels),
+						promhttp.InstrumentHandlerResponseSize(m.responseSize.MustCurryWith(labels), next),
+					),
+				),
+			),
 		).ServeHTTP(w, r)
 	})
 }
+
+// cacheMiss counts a read of keyspace that didn't find the key
+func (m *appMetrics) cacheMiss(keyspace string, findXTriggered bool) {
+	m.cacheMisses.WithLabelValues(keyspace, strconv.FormatBool(findXTriggered)).Inc()
+}
+
+// clientUA returns the client name in User-Agent, truncated to bound metrics cardinality
+func clientUA(r *http.Request) string {
+	const maxUA = 12
+	ua := strings.Split(r.UserAgent(), "/")[0]
+	if len(ua) > maxUA {
+		ua = ua[:maxUA]
+	}
+	return ua
+}
+
+// registerPoolStats registers metrics of the Redis connection pool of rdb, labeled pool, read on
+// scrape. All the clients of redis.NewUniversalClient have pool stats, the cluster client sums those
+// of the nodes.
+func registerPoolStats(rdb redis.UniversalClient, pool string) {
+	client, ok := rdb.(interface{ PoolStats() *redis.PoolStats })
+	if !ok {
+		return
+	}
+	stats := []struct {
+		name    string
+		help    string
+		counter bool
+		value   func(*redis.PoolStats) uint32
+	}{
+		{"hits_total", "A counter of free connections found in the pool.", true,
+			func(s *redis.PoolStats) uint32 { return s.Hits }},
+		{"misses_total", "A counter of free connections not found in the pool.", true,
+			func(s *redis.PoolStats) uint32 { return s.Misses }},
+		{"timeouts_total", "A counter of waits for a connection that timed out.", true,
+			func(s *redis.PoolStats) uint32 { return s.Timeouts }},
+		{"stale_conns_total", "A counter of stale connections removed from the pool.", true,
+			func(s *redis.PoolStats) uint32 { return s.StaleConns }},
+		{"total_conns", "The number of connections in the pool.", false,
+			func(s *redis.PoolStats) uint32 { return s.TotalConns }},
+		{"idle_conns", "The number of idle connections in the pool.", false,
+			func(s *redis.PoolStats) uint32 { return s.IdleConns }},
+	}
+	for _, stat := range stats {
+		stat := stat
+		value := func() float64 { return float64(stat.value(client.PoolStats())) }
+		labels := prometheus.Labels{"pool": pool}
+		if stat.counter {
+			prometheus.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+				Namespace: AppName, Subsystem: "redis_pool", Name: stat.name, Help: stat.help, ConstLabels: labels}, value))
+		} else {
+			prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Namespace: AppName, Subsystem: "redis_pool", Name: stat.name, Help: stat.help, ConstLabels: labels}, value))
+		}
+	}
+}
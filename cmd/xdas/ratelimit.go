@@ -0,0 +1,74 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"xdas/internal/auth"
+	"xdas/internal/ratelimit"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// rateLimit is a middleware that rejects requests over the rate limit of the client on the
+// keyspace with 429 and Retry-After. Requests are let through if the shared limit in Redis fails.
+func (s *Server) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l := &s.cfg().RateLimit
+		if !l.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+		keyspace := chi.URLParam(r, "keyspace")
+		client, label := rateLimitClient(l.By, r)
+		ok, wait, err := l.Allow(r.Context(), client, keyspace)
+		if err != nil {
+			s.reqLog(r).Error("Rate limit error", "err", err)
+			s.metrics.redisRateErr.Inc()
+		}
+		if !ok {
+			s.metrics.throttled.WithLabelValues(keyspace, label).Inc()
+			w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(wait.Seconds())))))
+			s.sendError(w, r, errCodeRateLimited, "rate limit exceeded")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimitClient returns the client identity of the request, and its metrics label. Requests
+// without a User-Agent or principal are identified by IP.
+func rateLimitClient(by string, r *http.Request) (client, label string) {
+	switch by {
+	case ratelimit.ByUA:
+		if ua := clientUA(r); ua != "" {
+			return ua, ua
+		}
+	case ratelimit.ByPrincipal:
+		if p := auth.FromContext(r.Context()); p != nil {
+			return p.Name, p.MetricsLabel()
+		}
+	}
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return ip, ratelimit.ByIP
+}
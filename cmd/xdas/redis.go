@@ -17,14 +17,37 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"net/http"
+	"time"
 	"xdas/internal/magicbyte"
-
-	"github.com/go-redis/redis/v7"
+	"xdas/internal/store"
 )
 
-func redisGet(rClient redis.UniversalClient, key string) (magicbyte.MagicByte, []byte, error) {
-	result, err := rClient.Get(key).Bytes()
+// redisContext returns the context of the Redis calls of r, bounded by the shortest redisTimeout of
+// ksConfs if any is set
+func redisContext(r *http.Request, ksConfs ...*KeyspaceConfig) (context.Context, context.CancelFunc) {
+	return withRedisTimeout(r.Context(), ksConfs...)
+}
+
+// withRedisTimeout returns ctx bounded by the shortest redisTimeout of ksConfs if any is set
+func withRedisTimeout(ctx context.Context, ksConfs ...*KeyspaceConfig) (context.Context, context.CancelFunc) {
+	var timeout time.Duration
+	for _, ksConf := range ksConfs {
+		if t := ksConf.redisTimeout; t > 0 && (timeout == 0 || t < timeout) {
+			timeout = t
+		}
+	}
+	if timeout == 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// storeGet returns the value of key and its magicByte, store.ErrNotFound if it doesn't exist
+func storeGet(ctx context.Context, st store.Store, key string) (magicbyte.MagicByte, []byte, error) {
+	result, err := st.Get(ctx, key)
 	if err != nil {
 		return magicbyte.MagicByte{}, result, err
 	}
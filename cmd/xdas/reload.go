@@ -0,0 +1,135 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"sort"
+	"sync"
+	"time"
+	"xdas/internal/conversion"
+)
+
+// reloadState is the outcome of the last reload
+type reloadState struct {
+	mu       sync.Mutex // also serializes reloads
+	time     time.Time
+	err      error
+	added    []string
+	removed  []string
+	retiring sync.WaitGroup // closing of the keyspaces replaced by reloads
+}
+
+// reloadOnSignal reloads config on every signal received, until the channel is closed
+func (s *Server) reloadOnSignal(sig chan os.Signal) {
+	for range sig {
+		s.log.Info("Reloading config on signal")
+		if err := s.reloadConfig(); err != nil {
+			s.log.Error("Config reload failed", "err", err)
+		}
+	}
+}
+
+// reloadConfig re-reads the config files and swaps in the keyspace related settings: Keyspaces,
+// Multipart, DeviceMapping, ValidateContent, MaxDecompressedSize, Auth and RateLimit (buckets kept
+// in memory start over). Web, HClient, Store, Redis, Tracing, Health, Watch, Changes and the other
+// settings are bound at startup and kept as is. An invalid config is rejected without affecting
+// the running one. Keyspaces whose config is unchanged keep their FindX and Notify running, those
+// changed or added are started before the swap, and those changed or removed are closed in the
+// background after it, so it returns without waiting for their queues.
+func (s *Server) reloadConfig() error {
+	s.reload.mu.Lock()
+	defer s.reload.mu.Unlock()
+	s.reload.time = time.Now()
+	s.reload.added, s.reload.removed = nil, nil
+
+	old := s.cfg()
+	if old.source.empty() {
+		s.reload.err = errors.New("no config file to reload")
+		return s.reload.err
+	}
+	config, err := loadConfig(s.log, old.source)
+	if err != nil {
+		s.reload.err = err
+		return err
+	}
+	config.Verbose = old.Verbose
+	config.NoMetrics = old.NoMetrics
+	config.Web = old.Web
+	config.HClient = old.HClient
+	config.Store = old.Store
+	config.Redis = old.Redis
+	config.Watch = old.Watch
+	config.Changes = old.Changes
+	config.Health = old.Health
+	config.Admin = old.Admin
+	config.Tracing = old.Tracing
+	config.RateLimit.Redis = s.redis
+
+	added := make(map[string]*KeyspaceConfig)
+	removed := make(map[string]*KeyspaceConfig)
+	for keyspace, ksConf := range config.Keyspaces {
+		if o, ok := old.Keyspaces[keyspace]; ok && bytes.Equal(o.raw, ksConf.raw) {
+			config.Keyspaces[keyspace] = o
+			continue
+		}
+		added[keyspace] = ksConf
+	}
+	for keyspace, o := range old.Keyspaces {
+		if config.Keyspaces[keyspace] != o {
+			removed[keyspace] = o
+		}
+	}
+
+	addedNames := sortedKeys(added)
+	if err := conversion.AddKeyspaces(addedNames); err != nil {
+		s.log.Error("Error adding conversion metrics", "err", err)
+	}
+
+	// the replaced ones hand over their metrics, so those of the new ones can be registered
+	for _, o := range removed {
+		o.FindX.UnregisterMetrics()
+		o.Notify.UnregisterMetrics()
+	}
+	s.newFindX(added)
+	s.newNotify(added)
+	s.newCache(added)
+	s.config.Store(config)
+	conversion.SetMaxDecompressedSize(config.MaxDecompressedSize, config.decompressLimits())
+	s.reload.retiring.Add(1)
+	go func() {
+		defer s.reload.retiring.Done()
+		s.closeKeyspaces(removed)
+	}()
+
+	s.reload.err = nil
+	s.reload.added = addedNames
+	s.reload.removed = sortedKeys(removed)
+	s.log.Info("Config reloaded", "added", s.reload.added, "removed", s.reload.removed)
+	return nil
+}
+
+func sortedKeys(m map[string]*KeyspaceConfig) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"xdas/internal/conversion"
+)
+
+// reloadTestConfig returns a config with the keyspaces of ttls, each notifying url
+func reloadTestConfig(url string, ttls map[string]string) string {
+	keyspaces := make([]string, 0, len(ttls))
+	for keyspace, ttl := range ttls {
+		keyspaces = append(keyspaces, fmt.Sprintf(`%q: {"ttl": %q, "Notify": {"Enabled": true, "URL": %q}}`, keyspace, ttl, url))
+	}
+	return `{"Admin": {"Token": "secret"}, "Keyspaces": {` + strings.Join(keyspaces, ", ") + `}}`
+}
+
+func TestReloadConfig(t *testing.T) {
+	notified := make(chan string, 10)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		notified <- r.Header.Get("Xdas-Keyspace")
+	}))
+	defer hook.Close()
+	defer conversion.SetMaxDecompressedSize(0, nil)
+
+	s, _ := newTestServer(t, reloadTestConfig(hook.URL, map[string]string{"keep": "1h", "change": "1h", "drop": "1h"}))
+	file := s.cfg().source.Files[0]
+	old := s.cfg().Keyspaces
+	admin := map[string]string{"Authorization": "Bearer secret"}
+
+	type reloadResult struct {
+		Data struct {
+			Status  string
+			Error   string
+			Added   []string
+			Removed []string
+		}
+	}
+	tests := []struct {
+		name        string
+		conf        string
+		wantStatus  int
+		wantResult  string // status of the reload
+		wantAdded   []string
+		wantRemoved []string
+	}{
+		{"keep, restart, stop and start", reloadTestConfig(hook.URL, map[string]string{"keep": "1h", "change": "2h", "add": "1h"}),
+			http.StatusOK, "ok", []string{"add", "change"}, []string{"change", "drop"}},
+		{"unchanged", reloadTestConfig(hook.URL, map[string]string{"keep": "1h", "change": "2h", "add": "1h"}),
+			http.StatusOK, "ok", []string{}, []string{}},
+		{"invalid json", `{"Keyspaces": {`, http.StatusBadRequest, "rejected", nil, nil},
+		{"invalid field", `{"Keyspaces": {"keep": {"ttl": 1}}}`, http.StatusBadRequest, "rejected", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(file, []byte(tt.conf), 0600); err != nil {
+				t.Fatal(err)
+			}
+			before := s.cfg()
+			w := serve(s, http.MethodPost, "/admin/reload", "", admin)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status got: %d, want: %d, body: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			var result reloadResult
+			if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+				t.Fatalf("body got: %s, error: %v", w.Body, err)
+			}
+			if result.Data.Status != tt.wantResult {
+				t.Errorf("status got: %s, want: %s", result.Data.Status, tt.wantResult)
+			}
+			if tt.wantResult == "rejected" {
+				if result.Data.Error == "" {
+					t.Error("error got empty")
+				}
+				if s.cfg() != before {
+					t.Error("config got replaced by a rejected reload")
+				}
+				return
+			}
+			if !slices.Equal(result.Data.Added, tt.wantAdded) || !slices.Equal(result.Data.Removed, tt.wantRemoved) {
+				t.Errorf("added, removed got: %q, %q, want: %q, %q", result.Data.Added, result.Data.Removed,
+					tt.wantAdded, tt.wantRemoved)
+			}
+		})
+	}
+
+	keyspaces := s.cfg().Keyspaces
+	if keyspaces["keep"] != old["keep"] {
+		t.Error("keep got replaced, want kept")
+	}
+	if keyspaces["change"] == old["change"] || keyspaces["change"].ttl != 2*time.Hour {
+		t.Errorf("change got: %+v, want a new keyspace with ttl 2h", keyspaces["change"])
+	}
+	if w := serve(s, http.MethodGet, "/v2/drop/A", "", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("GET drop got: %d, want: %d", w.Code, http.StatusBadRequest)
+	}
+
+	// the kept, restarted and started Notify send the changes of their keyspace
+	for _, keyspace := range []string{"keep", "change", "add"} {
+		if w := serve(s, http.MethodPut, "/v2/"+keyspace+"/A", "x", nil); w.Code != http.StatusOK {
+			t.Fatalf("PUT %s got: %d, want: %d", keyspace, w.Code, http.StatusOK)
+		}
+		select {
+		case got := <-notified:
+			if got != keyspace {
+				t.Errorf("notified got: %s, want: %s", got, keyspace)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("notified got nothing for %s", keyspace)
+		}
+	}
+}
+
+func TestReloadConfigWithoutFile(t *testing.T) {
+	s, _ := newTestServer(t, `{"Keyspaces": {"gs": {"ttl": "1h"}}}`)
+	config := *s.cfg()
+	config.source = configSource{}
+	s.config.Store(&config)
+	if err := s.reloadConfig(); err == nil {
+		t.Error("reloadConfig() got nil error, want error")
+	}
+	if s.cfg() != &config {
+		t.Error("config got replaced by a failed reload")
+	}
+}
@@ -0,0 +1,50 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"net/http"
+	"xdas/internal/logger"
+	"xdas/internal/logger/weblog"
+	"xdas/internal/requestid"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestID is a middleware that takes the X-Request-Id of the request, or generates one if it
+// is missing or invalid, and echoes it in the response. The ID is added to the request context,
+// with a logger that adds it to every record, see reqLog.
+func (s *Server) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+		w.Header().Set(requestid.Header, id)
+		weblog.AddAttrs(r.Context(), "requestId", id)
+		trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("xdas.request_id", id))
+		ctx := requestid.NewContext(r.Context(), id)
+		ctx = logger.NewContext(ctx, s.log.With("requestId", id))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// reqLog returns the logger of the request, which adds its request ID to every record
+func (s *Server) reqLog(r *http.Request) *logger.Logger {
+	return logger.FromContext(r.Context(), s.log)
+}
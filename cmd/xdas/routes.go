@@ -17,58 +17,82 @@
 package main
 
 import (
+	"xdas/internal/auth"
 	"xdas/internal/logger/weblog"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-// MaxSize is the max message size we will accept
+// MaxSize is the default max message size we will accept, keyspaces can override it with maxSize
 const MaxSize = 1000000
 
 func (s *Server) addRoutes() {
-	if s.config.Verbose {
+	if s.cfg().Verbose {
 		s.router.Use(weblog.WebLogChiMiddleware(s.log))
 		// s.router.Use(s.webLogging)
 	}
-	s.router.Use(middleware.RequestSize(MaxSize))
+	if s.cfg().Tracing.Enabled {
+		s.router.Use(s.trace)
+	}
+	s.router.Use(s.requestID, s.requestSize)
 
 	s.router.Route(xdasAPIPath, func(r chi.Router) {
+		r.Use(s.authenticate)
 		r.Route("/multi", func(r chi.Router) {
 			r.Use(addURLParamKeyspace("multi"))
-			if !s.config.NoMetrics {
+			if !s.cfg().NoMetrics {
 				r.Use(s.metrics.appMetrics)
 			}
+			r.Use(s.rateLimit)
 			r.Get("/{id}", s.handleFuncXdasMultiGet)
 			// r.Put("/{id}", s.handleFuncXdasMultiPut)
 			// r.Post("/{id}", s.handleFuncXdasMultiPut)
 		})
-		r.Route("/{keyspace}", func(r chi.Router) {
+		r.Route("/_changes/{keyspace}", func(r chi.Router) {
 			r.Use(s.validateKeyspace)
-			if !s.config.NoMetrics {
+			if !s.cfg().NoMetrics {
 				r.Use(s.metrics.appMetrics)
 			}
-			r.Get("/{id}", s.handleFuncXdasGet)
-			r.Put("/{id}", s.handleFuncXdasPut)
-			r.Post("/{id}", s.handleFuncXdasPut)
-			r.Delete("/{id}", s.handleFuncXdasDel)
+			r.Use(s.rateLimit)
+			r.With(s.authorize(auth.Read)).Get("/", s.handleFuncXdasChanges)
+		})
+		r.Route("/{keyspace}", func(r chi.Router) {
+			r.Use(s.validateKeyspace, s.requestSize)
+			if !s.cfg().NoMetrics {
+				r.Use(s.metrics.appMetrics)
+			}
+			r.Use(s.rateLimit)
+			r.With(s.authorize(auth.Read)).Get("/{id}", s.handleFuncXdasGet)
+			r.With(s.authorize(auth.Write)).Put("/{id}", s.handleFuncXdasPut)
+			r.With(s.authorize(auth.Write)).Post("/{id}", s.handleFuncXdasPut)
+			r.With(s.authorize(auth.Delete)).Delete("/{id}", s.handleFuncXdasDel)
+			if s.cfg().Watch.Enabled {
+				r.With(s.authorize(auth.Read)).Get("/{id}/watch", s.handleFuncXdasWatch)
+			}
 		})
 
 		r.Route("/inc/{keyspace}", func(r chi.Router) {
 			r.Use(s.validateAtomicKeyspace)
-			if !s.config.NoMetrics {
+			if !s.cfg().NoMetrics {
 				r.Use(s.metrics.appMetrics)
 			}
+			r.Use(s.rateLimit)
 			// r.Get("/{id}", s.handleFuncXdasGet)
-			r.Put("/{id}", s.handleFuncXdasAtomicInc)
-			r.Post("/{id}", s.handleFuncXdasAtomicInc)
+			r.With(s.authorize(auth.Inc)).Put("/{id}", s.handleFuncXdasAtomicInc)
+			r.With(s.authorize(auth.Inc)).Post("/{id}", s.handleFuncXdasAtomicInc)
 			// r.Delete("/{id}", s.handleFuncXdasDel)
 		})
 	})
 
+	s.router.Route("/admin", func(r chi.Router) {
+		r.Use(s.adminAuth)
+		r.Post("/reload", s.handleAdminReload)
+		r.Get("/config", s.handleAdminConfig)
+	})
+
 	s.router.Get("/metrics", promhttp.Handler().ServeHTTP)
-	// s.router.Get("/config", s.handleConfig)
 	s.router.Get("/version", s.handleVersion)
 	s.router.Get("/healthz", s.handleHealthz)
+	s.router.Get("/readyz", s.handleReadyz)
 }
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"xdas/internal/conversion"
+	"xdas/internal/magicbyte"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+var errInvalidJSON = errors.New("invalid JSON")
+
+// compileSchema compiles the JSON Schema file of ksConf, if set, which requires JSON input. The
+// schema is added to raw, so changes to the file are applied on reload.
+func compileSchema(ksConf *KeyspaceConfig) error {
+	if ksConf.Schema == "" {
+		return nil
+	}
+	if ksConf.Input.magicByte.GetCTV() != magicbyte.ContentTypeJson {
+		return errors.New("input contentType must be application/json")
+	}
+	b, err := os.ReadFile(ksConf.Schema)
+	if err != nil {
+		return err
+	}
+	// compiled from b, so the schema is the one compared on reload even if the file is replaced
+	c := jsonschema.NewCompiler()
+	if err = c.AddResource(ksConf.Schema, bytes.NewReader(b)); err != nil {
+		return err
+	}
+	schema, err := c.Compile(ksConf.Schema)
+	if err != nil {
+		return err
+	}
+	ksConf.schema = schema
+	ksConf.raw = append(ksConf.raw, b...)
+	return nil
+}
+
+// validateSchema decompresses data and validates it against schema. Violations are returned as
+// *jsonschema.ValidationError.
+func validateSchema(schema *jsonschema.Schema, keyspace string, magicByte magicbyte.MagicByte, data []byte) error {
+	data, err := conversion.DecompressLimit(magicByte.GetCEV(), data, conversion.MaxDecompressedSize(keyspace))
+	if err != nil {
+		return err
+	}
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err = dec.Decode(&v); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidJSON, err)
+	}
+	if _, err = dec.Token(); err != io.EOF {
+		return fmt.Errorf("%w: data after the top-level value", errInvalidJSON)
+	}
+	return schema.Validate(v)
+}
+
+// sendSchemaErr sends 400 with the path and message of each violation as details
+func (s *Server) sendSchemaErr(w http.ResponseWriter, r *http.Request, err error) {
+	var validationError *jsonschema.ValidationError
+	switch {
+	case errors.As(err, &validationError):
+		s.metrics.schemaInvalid.WithLabelValues(chi.URLParam(r, "keyspace")).Inc()
+		s.sendError(w, r, errCodeSchemaViolation, "request body does not validate against the keyspace schema",
+			violations(nil, validationError)...)
+	case errors.Is(err, errInvalidJSON):
+		s.sendError(w, r, errCodeInvalidContent, err.Error())
+	default:
+		s.sendConversionErr(w, r, err, errCodeInvalidContent)
+	}
+}
+
+// violations appends the leaf errors of e, which name the values that are invalid
+func violations(v []string, e *jsonschema.ValidationError) []string {
+	if len(e.Causes) == 0 {
+		path := e.InstanceLocation
+		if path == "" {
+			path = "/"
+		}
+		return append(v, path+": "+e.Message)
+	}
+	for _, cause := range e.Causes {
+		v = violations(v, cause)
+	}
+	return v
+}
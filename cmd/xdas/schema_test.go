@@ -0,0 +1,135 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"xdas/internal/conversion"
+	"xdas/internal/magicbyte"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const testSchema = `{
+	"type": "object",
+	"properties": {"name": {"type": "string"}, "tags": {"type": "array", "items": {"type": "string"}}},
+	"required": ["name"]
+}`
+
+func newTestSchema(t *testing.T) *KeyspaceConfig {
+	file := filepath.Join(t.TempDir(), "schema.json")
+	if err := os.WriteFile(file, []byte(testSchema), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ksConf := &KeyspaceConfig{Schema: file, raw: []byte("{}")}
+	ksConf.Input.magicByte = magicbyte.New("", "application/json", 0)
+	if err := compileSchema(ksConf); err != nil {
+		t.Fatal("compileSchema() returned error:", err)
+	}
+	return ksConf
+}
+
+func TestCompileSchema(t *testing.T) {
+	ksConf := newTestSchema(t)
+	if ksConf.schema == nil {
+		t.Fatal("compileSchema() got nil schema")
+	}
+	if want := "{}" + testSchema; string(ksConf.raw) != want {
+		t.Errorf("raw got: %s, want: %s", ksConf.raw, want)
+	}
+
+	ksConf.Input.magicByte = magicbyte.New("", "application/x-protobuf", 0)
+	if err := compileSchema(ksConf); err == nil {
+		t.Error("compileSchema() with protobuf input got nil error, want error")
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	schema := newTestSchema(t).schema
+	plain := magicbyte.New("", "application/json", 0)
+	zstd := magicbyte.New("zstd", "application/json", 0)
+	compress := func(s string) []byte {
+		b, err := conversion.Compress(zstd.GetCEV(), []byte(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	tests := []struct {
+		name           string
+		magicByte      magicbyte.MagicByte
+		data           []byte
+		wantViolations []string // prefixes of the violations
+		wantInvalid    bool
+	}{
+		{"valid", plain, []byte(`{"name":"a","tags":["b"]}`), nil, false},
+		{"violation", plain, []byte(`{"name":1}`), []string{"/name: "}, false},
+		{"nested violation", plain, []byte(`{"name":"a","tags":["b",2]}`), []string{"/tags/1: "}, false},
+		{"missing property", plain, []byte(`{}`), []string{"/: "}, false},
+		{"trailing data", plain, []byte(`{"name":"a"} {}`), nil, true},
+		{"invalid json", plain, []byte(`{"name":`), nil, true},
+		{"zstd valid", zstd, compress(`{"name":"a"}`), nil, false},
+		{"zstd violation", zstd, compress(`{"name":true}`), []string{"/name: "}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSchema(schema, "test", tt.magicByte, tt.data)
+			if tt.wantInvalid {
+				if !errors.Is(err, errInvalidJSON) {
+					t.Errorf("validateSchema() got: %v, want errInvalidJSON", err)
+				}
+				return
+			}
+			if tt.wantViolations == nil {
+				if err != nil {
+					t.Errorf("validateSchema() returned error: %v", err)
+				}
+				return
+			}
+			var validationError *jsonschema.ValidationError
+			if !errors.As(err, &validationError) {
+				t.Fatalf("validateSchema() got: %v, want *jsonschema.ValidationError", err)
+			}
+			v := violations(nil, validationError)
+			if len(v) != len(tt.wantViolations) {
+				t.Fatalf("violations() got: %q, want: %q", v, tt.wantViolations)
+			}
+			for i, want := range tt.wantViolations {
+				if !strings.HasPrefix(v[i], want) {
+					t.Errorf("violations() got: %q, want prefix: %q", v[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateSchemaDecompressLimit(t *testing.T) {
+	schema := newTestSchema(t).schema
+	zstd := magicbyte.New("zstd", "application/json", 0)
+	data, _ := conversion.Compress(zstd.GetCEV(), bytes.Repeat([]byte(" "), 1000))
+	conversion.SetMaxDecompressedSize(100, nil)
+	defer conversion.SetMaxDecompressedSize(0, nil)
+	if err := validateSchema(schema, "test", zstd, data); err == nil || errors.Is(err, errInvalidJSON) {
+		t.Errorf("validateSchema() over the limit got: %v, want decompression error", err)
+	}
+}
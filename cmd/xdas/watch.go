@@ -0,0 +1,168 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+	"xdas/internal/conversion"
+	"xdas/internal/keyspaces"
+	"xdas/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultWatchKeepAlive = 15 * time.Second
+	defaultMaxWatchers    = 1000
+
+	// keyspaceChannel is the prefix of Redis keyspace notification channels, requires
+	// notify-keyspace-events to include at least "K$gx" on the Redis servers
+	keyspaceChannel = "__keyspace@0__:"
+)
+
+// handleFuncXdasWatch streams Server-Sent Events whenever the record is written, deleted or expires.
+// The current value, if any, is sent as the first event. Events are "set", "del" and "expired",
+// the data of each event is a JSON object in the same format as a change of the changes API.
+func (s *Server) handleFuncXdasWatch(w http.ResponseWriter, r *http.Request) {
+	keyspace := chi.URLParam(r, "keyspace")
+	ksConf, ok := s.cfg().Keyspaces[keyspace]
+	if !ok { // should not happen, handled by validateKeyspace
+		s.sendError(w, r, errCodeInvalidKeyspace, "unknown keyspace")
+		return
+	}
+	id := getID(r)
+	key := redisKey(keyspace, id)
+
+	// each watcher subscribes on a Redis connection of its own
+	if n := s.watchers.Add(1); n > int64(s.cfg().Watch.MaxWatchers) {
+		s.watchers.Add(-1)
+		w.Header().Set("Retry-After", "1")
+		s.sendError(w, r, errCodeUnavailable, "too many watchers")
+		return
+	}
+	s.metrics.watchers.Inc()
+	defer func() {
+		s.watchers.Add(-1)
+		s.metrics.watchers.Dec()
+	}()
+
+	// In cluster mode the channel hashes to the same slot as the key, so the subscription
+	// is made on the node that publishes the notification
+	sub := s.redis.Subscribe(r.Context(), keyspaceChannel+key)
+	defer sub.Close()
+	if _, err := sub.Receive(r.Context()); err != nil {
+		s.sendRedisReadErr(w, r, err)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-type", "text/event-stream")
+	w.Header().Set("Cache-control", "no-cache")
+	w.Header().Set("X-accel-buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	keepAlive := s.cfg().Watch.keepAlive
+	rc.SetWriteDeadline(time.Now().Add(keepAlive + s.web.WriteTimeout))
+	s.sendWatchValue(w, r, ksConf, keyspace, id, key)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	ch := sub.Channel()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.closing:
+			return
+		case <-ticker.C:
+			io.WriteString(w, ": keepalive\n\n")
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			switch msg.Payload {
+			case "set", "incrby", "incr", "decrby", "decr", "incrbyfloat", "append", "setrange",
+				"rename_to", "restore", "copy_to":
+				s.sendWatchValue(w, r, ksConf, keyspace, id, key)
+			case "del", "rename_from":
+				writeWatchEvent(w, change{Keyspace: keyspace, ID: id, Op: "del", Timestamp: time.Now().UnixMilli()})
+			case "expired", "evicted":
+				writeWatchEvent(w, change{Keyspace: keyspace, ID: id, Op: "expired", Timestamp: time.Now().UnixMilli()})
+			default: // expire, persist and others that don't change the value
+				continue
+			}
+		}
+		rc.SetWriteDeadline(time.Now().Add(keepAlive + s.web.WriteTimeout))
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// sendWatchValue sends a "set" event with the current value in Output format, nothing is sent
+// if the record doesn't exist
+func (s *Server) sendWatchValue(w io.Writer, r *http.Request, ksConf *KeyspaceConfig, keyspace, id, key string) {
+	ctx, cancel := redisContext(r, ksConf)
+	defer cancel()
+	result, ttl, err := s.store.GetTTL(ctx, key)
+	if err != nil {
+		if err != store.ErrNotFound {
+			s.reqLog(r).Error("Redis read error", "err", err)
+			s.metrics.redisReadErr.Inc()
+		}
+		return
+	}
+
+	c := change{Keyspace: keyspace, ID: id, Op: "set", TTL: int64(ttl / time.Second),
+		Timestamp: time.Now().UnixMilli()}
+	if ksConf.Kind == keyspaces.KSAtomic { // Atomic keyspaces are native Redis string type
+		c.Payload = result
+		writeWatchEvent(w, c)
+		return
+	}
+
+	magicByte, data, err := redisParseResult(result)
+	if err != nil {
+		s.reqLog(r).Error("Watch read error", "keyspace", keyspace, "key", key, "err", err)
+		return
+	}
+	if !s.allowRead(r, ksConf, keyspace, key, magicByte) {
+		return
+	}
+	magicByte, data, err = conversion.ConvertContext(r.Context(), keyspace, magicByte, ksConf.Output.magicByte, data)
+	if err != nil {
+		s.reqLog(r).Error("Conversion error", "keyspace", keyspace, "key", key, "err", err)
+		return
+	}
+	c.MagicByte = int(magicByte.Get())
+	c.ContentType = magicByte.GetContentType()
+	c.ContentEncoding = magicByte.GetContentEncoding()
+	c.Payload = data
+	writeWatchEvent(w, c)
+}
+
+func writeWatchEvent(w io.Writer, c change) {
+	data, _ := json.Marshal(c)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", c.Op, data)
+}
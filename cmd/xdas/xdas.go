@@ -27,16 +27,19 @@ import (
 	"os"
 	"os/signal"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 	"xdas/internal/conversion"
 	"xdas/internal/keyspaces"
 	"xdas/internal/logger"
 	"xdas/internal/rediscrypto"
+	"xdas/internal/store"
+	"xdas/internal/tracing"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-redis/redis/v7"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
 )
 
 var (
@@ -57,14 +60,24 @@ const (
 
 // A Server holds all the servers and configurations
 type Server struct {
-	config  *Configuration
-	router  *chi.Mux
-	web     *http.Server
-	hClient *http.Client
-	redis   redis.UniversalClient
-	metrics *appMetrics
-	bufPool sync.Pool
-	log     *logger.Logger
+	config   atomic.Pointer[Configuration] // swapped on reload, use cfg()
+	router   *chi.Mux
+	web      *http.Server
+	hClient  *http.Client
+	store    store.Store
+	redis    redis.UniversalClient // nil with the memory store
+	blocking redis.UniversalClient // for blocking reads of long polls, nil with the memory store
+	polls    chan struct{}         // semaphore of the long polls, of Changes.MaxPolls
+	watchers atomic.Int64          // open watchers, up to Watch.MaxWatchers
+	metrics  *appMetrics
+	bufPool  sync.Pool
+	log      *logger.Logger
+	closing  chan struct{} // closed when the web server starts shutting down
+	draining atomic.Bool   // set on shutdown, /readyz returns 503 from then on
+	reload   reloadState
+
+	// invalidations subscribes to cache invalidations of other pods once
+	invalidations sync.Once
 }
 
 func main() {
@@ -84,36 +97,69 @@ func main() {
 		logger.Fatal(err)
 	}
 
-	redisClient := redis.NewUniversalClient(config.Redis.ClientConfig)
+	if err := config.Tracing.Start(AppName, AppVersion); err != nil {
+		logger.Fatal(err)
+	}
+
+	var st store.Store
+	var redisClient, blockingClient redis.UniversalClient
+	if config.Store == store.BackendMemory {
+		logger.Info("Using the memory store, values are lost on restart")
+		st = store.NewMemory()
+	} else {
+		redisClient = redis.NewUniversalClient(config.Redis.ClientConfig)
+		blockingOpts := *config.Redis.ClientConfig
+		blockingOpts.PoolSize = config.Changes.MaxPolls
+		blockingOpts.MinIdleConns = 0
+		blockingClient = redis.NewUniversalClient(&blockingOpts)
+		if config.Tracing.Enabled {
+			redisClient.AddHook(tracing.RedisHook{})
+			blockingClient.AddHook(tracing.RedisHook{})
+		}
+		st = store.NewRedis(redisClient)
+	}
+	config.RateLimit.Redis = redisClient
 	s := &Server{
-		config:  config,
-		router:  chi.NewRouter(),
-		hClient: config.HClient.Client,
-		redis:   redisClient,
-		metrics: newMetrics(),
-		bufPool: sync.Pool{New: func() interface{} { return new(bytes.Buffer) }},
-		log:     logger,
+		router:   chi.NewRouter(),
+		hClient:  config.HClient.Client,
+		store:    st,
+		redis:    redisClient,
+		blocking: blockingClient,
+		polls:    make(chan struct{}, config.Changes.MaxPolls),
+		metrics:  newMetrics(),
+		bufPool:  sync.Pool{New: func() interface{} { return new(bytes.Buffer) }},
+		log:      logger,
 	}
+	s.config.Store(config)
+	registerPoolStats(redisClient, "default")
+	registerPoolStats(blockingClient, "blocking")
 
 	s.newWebServer()
 	s.addRoutes()
 
 	s.newConvert()
-	s.newFindX()
+	s.newFindX(config.Keyspaces)
+	s.newNotify(config.Keyspaces)
+	s.newCache(config.Keyspaces)
 
-	val := s.redis.ClusterInfo().Val()
-	logger.Info("Redis cluster info: " + val)
-	val = s.redis.ClusterNodes().Val()
-	logger.Info("Redis cluster nodes: " + val)
+	if s.redis != nil {
+		val := s.redis.ClusterInfo(context.Background()).Val()
+		logger.Info("Redis cluster info: " + val)
+		val = s.redis.ClusterNodes(context.Background()).Val()
+		logger.Info("Redis cluster nodes: " + val)
+	}
 
 	done := make(chan bool)
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 	go s.shutdown(quit, done)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go s.reloadOnSignal(hup)
 
 	logger.Info("Server is ready to handle requests", "addr", s.web.Addr)
 
-	if tls := s.config.Web.TLS; tls.CertFile != "" && tls.KeyFile != "" {
+	if tls := s.cfg().Web.TLS; tls.CertFile != "" && tls.KeyFile != "" {
 		if err := s.web.ListenAndServeTLS(tls.CertFile, tls.KeyFile); err != http.ErrServerClosed {
 			logger.Fatalf("Could not listen on %s: %v\n", s.web.Addr, err)
 		}
@@ -127,20 +173,30 @@ func main() {
 	logger.Info("Shutdown complete")
 }
 
+// cfg returns the current config
+func (s *Server) cfg() *Configuration {
+	return s.config.Load()
+}
+
 func (s *Server) newWebServer() {
-	// s.config.Web.Handler = h2c.NewHandler(s.router, &http2.Server{})
-	s.config.Web.Server.Handler = s.router
-	s.web = s.config.Web.Server
+	// s.cfg().Web.Handler = h2c.NewHandler(s.router, &http2.Server{})
+	s.cfg().Web.Server.Handler = s.router
+	// with CaFile, client certs are required unless the current Auth can do without them
+	s.cfg().Web.Server.TLSConfig = s.cfg().Web.TLS.GetServerTLS(func() bool { return s.cfg().Auth.CertOptional() })
+	s.web = s.cfg().Web.Server
+	s.closing = make(chan struct{})
+	s.web.RegisterOnShutdown(func() { close(s.closing) }) // end long-lived responses so Shutdown can complete
 }
 
-func (s *Server) newFindX() {
+func (s *Server) newFindX(ksConfs map[string]*KeyspaceConfig) {
 	s.log.Info("FindX is staring...")
-	for keyspace, ksConf := range s.config.Keyspaces {
+	for keyspace, ksConf := range ksConfs {
 		if ksConf.FindX.Enabled {
 			ksConf.FindX.Keyspace = keyspace
 			ksConf.FindX.Metrics.Keyspace = keyspace
 			ksConf.FindX.Metrics.PromNamespace = AppName
 			ksConf.FindX.HTTPClient = s.hClient
+			ksConf.FindX.Redis = s.redis
 			err := ksConf.FindX.Start()
 			if err != nil {
 				s.log.Error("Error starting FindX for", "keyspace", keyspace, "err", err)
@@ -149,33 +205,71 @@ func (s *Server) newFindX() {
 	}
 }
 
+func (s *Server) newNotify(ksConfs map[string]*KeyspaceConfig) {
+	s.log.Info("Notify is starting...")
+	for keyspace, ksConf := range ksConfs {
+		if ksConf.Notify.Enabled {
+			ksConf.Notify.Keyspace = keyspace
+			ksConf.Notify.Metrics.Keyspace = keyspace
+			ksConf.Notify.Metrics.PromNamespace = AppName
+			ksConf.Notify.HTTPClient = s.hClient
+			err := ksConf.Notify.Start()
+			if err != nil {
+				s.log.Error("Error starting Notify for", "keyspace", keyspace, "err", err)
+			}
+		}
+	}
+}
+
 func (s *Server) newConvert() {
-	keyspaces := make([]string, 0, len(s.config.Keyspaces))
-	for k := range s.config.Keyspaces {
+	keyspaces := make([]string, 0, len(s.cfg().Keyspaces))
+	for k := range s.cfg().Keyspaces {
 		keyspaces = append(keyspaces, k)
 	}
 	conversion.Init(prometheus.DefaultRegisterer, AppName, keyspaces)
+	conversion.SetMaxDecompressedSize(s.cfg().MaxDecompressedSize, s.cfg().decompressLimits())
 }
 
 func (s *Server) shutdown(quit chan os.Signal, done chan bool) {
 	<-quit
+	// fail readiness first, so load balancers stop sending requests before the server stops
+	s.draining.Store(true)
+	s.web.SetKeepAlivesEnabled(false)
+	s.log.Info("Draining...", "delay", s.cfg().Health.drainDelay)
+	time.Sleep(s.cfg().Health.drainDelay)
+
 	s.log.Info("Web server is shutting down...")
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	s.web.SetKeepAlivesEnabled(false)
 	if err := s.web.Shutdown(ctx); err != nil {
 		s.log.Info("Could not gracefully shutdown the server:", "err", err)
 	}
-	s.log.Info("Redis client is shutting down...")
-	if err := s.redis.Close(); err != nil {
-		s.log.Info("Failed to shut down Redis client cleanly", "err", err)
+	s.closeKeyspaces(s.cfg().Keyspaces) // before Redis, FindX stream queue uses it
+	s.reload.retiring.Wait()
+	s.log.Info("Store is shutting down...")
+	if err := s.store.Close(); err != nil { // closes the Redis client
+		s.log.Info("Failed to shut down the store cleanly", "err", err)
+	}
+	if s.blocking != nil {
+		s.blocking.Close()
 	}
+	if err := s.cfg().Tracing.Shutdown(ctx); err != nil {
+		s.log.Info("Failed to export the remaining spans", "err", err)
+	}
+	close(done)
+}
+
+// closeKeyspaces stops FindX and Notify of the keyspaces
+func (s *Server) closeKeyspaces(ksConfs map[string]*KeyspaceConfig) {
 	s.log.Info("FindX is shutting down...")
-	for _, ksConf := range s.config.Keyspaces {
+	for _, ksConf := range ksConfs {
 		ksConf.FindX.Close()
 	}
-	close(done)
+	s.log.Info("Notify is shutting down...")
+	for _, ksConf := range ksConfs {
+		ksConf.Notify.Close()
+	}
 }
 
 // addURLParamKeyspace adds Chi keyspace parameter. Used for Prometheus metrics
@@ -194,9 +288,9 @@ func addURLParamKeyspace(keyspace string) func(next http.Handler) http.Handler {
 func (s *Server) validateKeyspace(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		keyspace := chi.URLParam(r, "keyspace")
-		if _, ok := s.config.Keyspaces[keyspace]; !ok {
-			s.log.Info("Invalid keyspace", "keyspace", keyspace)
-			http.Error(w, "Invalid keyspace", http.StatusBadRequest)
+		if _, ok := s.cfg().Keyspaces[keyspace]; !ok {
+			s.reqLog(r).Info("Invalid keyspace", "keyspace", keyspace)
+			s.sendError(w, r, errCodeInvalidKeyspace, "unknown keyspace")
 			return
 		}
 		next.ServeHTTP(w, r)
@@ -208,11 +302,24 @@ func (s *Server) validateKeyspace(next http.Handler) http.Handler {
 func (s *Server) validateAtomicKeyspace(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		keyspace := chi.URLParam(r, "keyspace")
-		if c, ok := s.config.Keyspaces[keyspace]; !ok || c.Kind != keyspaces.KSAtomic {
-			s.log.Info("Invalid keyspace or not atomic inc", "keyspace", keyspace)
-			http.Error(w, "Invalid keyspace", http.StatusBadRequest)
+		if c, ok := s.cfg().Keyspaces[keyspace]; !ok || c.Kind != keyspaces.KSAtomic {
+			s.reqLog(r).Info("Invalid keyspace or not atomic inc", "keyspace", keyspace)
+			s.sendError(w, r, errCodeInvalidKeyspace, "unknown or not atomic keyspace")
 			return
 		}
 		next.ServeHTTP(w, r)
 	})
 }
+
+// requestSize is a middleware that limits the request body to the maxSize of the keyspace in the
+// URL. Before routing, or without a keyspace, it is the largest maxSize of all keyspaces.
+func (s *Server) requestSize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := s.cfg().maxSize
+		if ksConf, ok := s.cfg().Keyspaces[chi.URLParam(r, "keyspace")]; ok {
+			limit = ksConf.MaxSize
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"xdas/internal/logger"
+	"xdas/internal/rediscrypto"
+	"xdas/internal/store"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+const testEncryptionKey = "0a0b0c0d0e0f101112131415161718190a0b0c0d0e0f10111213141516171819"
+
+// testMetrics is shared by the test servers, as the metrics can only be registered once
+var testMetrics = newMetrics()
+
+// writeTestFile writes content to name in dir and returns its path
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	file := filepath.Join(dir, name)
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// newTestServer returns a server loaded from conf, a JSON config file, layered over a base file
+// pointing Redis to a miniredis. conf is written to the first file of the config source, so tests
+// can change it and reload. FindX and Notify of the keyspaces are closed at the end of the test.
+func newTestServer(t *testing.T, conf string) (*Server, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	dir := t.TempDir()
+	file := writeTestFile(t, dir, "config.json", conf)
+	base := writeTestFile(t, dir, "base.json", fmt.Sprintf(`{"Redis": {"ClientConfig": {"Addrs": [%q]}, "EncryptionKey": [%q]}}`,
+		mr.Addr(), testEncryptionKey))
+
+	log := logger.NewLogger()
+	// base is merged last, so conf can't point Redis elsewhere
+	config, err := loadConfig(log, newConfigSource(file+","+base, ""))
+	if err != nil {
+		t.Fatal("loadConfig() returned error:", err)
+	}
+	if _, err = rediscrypto.Init("AesGCM", config.Redis.EncryptionKey); err != nil {
+		t.Fatal(err)
+	}
+	rc := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rc.Close() })
+	s := &Server{
+		router:   chi.NewRouter(),
+		hClient:  config.HClient.Client,
+		store:    store.NewRedis(rc),
+		redis:    rc,
+		blocking: rc,
+		polls:    make(chan struct{}, config.Changes.MaxPolls),
+		metrics:  testMetrics,
+		bufPool:  sync.Pool{New: func() interface{} { return new(bytes.Buffer) }},
+		log:      log,
+	}
+	s.config.Store(config)
+	s.newWebServer()
+	s.addRoutes()
+	s.newFindX(config.Keyspaces)
+	s.newNotify(config.Keyspaces)
+	t.Cleanup(func() {
+		s.reload.retiring.Wait()
+		s.closeKeyspaces(s.cfg().Keyspaces)
+	})
+	return s, mr
+}
+
+// serve sends a request to the routes of s and returns the response
+func serve(s *Server, method, target, body string, header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	return w
+}
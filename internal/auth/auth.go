@@ -0,0 +1,205 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package auth authenticates requests as principals, by API key, bearer JWT, HMAC signature or
+// mTLS client certificate, and checks their permissions on keyspaces.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	HeaderAPIKey    = "X-Api-Key"
+	HeaderTimestamp = "Xdas-Timestamp"
+
+	DefaultMaxSkew = 5 * time.Minute
+
+	// AllKeyspaces grants permissions on all keyspaces
+	AllKeyspaces = "*"
+)
+
+var (
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrNoCredentials   = fmt.Errorf("%w: no credentials", ErrUnauthenticated)
+)
+
+// Permission is an operation a principal can be granted on a keyspace
+type Permission string
+
+const (
+	Read   Permission = "read"
+	Write  Permission = "write"
+	Delete Permission = "delete"
+	Inc    Permission = "inc"
+)
+
+// Auth holds the principals and how they authenticate. Invoke Validate before use.
+type Auth struct {
+	Enabled    bool
+	Principals map[string]*Principal
+	MaxSkew    time.Duration // max difference of HMAC timestamp from now, unit in ns, default 5m
+	JWT        JWT
+	apiKeys    map[[sha256.Size]byte]*Principal
+	subjects   map[string]*Principal
+	hmacKeys   bool // any principal has HMACKeys
+}
+
+// Principal is a client identity with its credentials and permissions
+type Principal struct {
+	Name         string
+	APIKeys      []string // sent in X-Api-Key header
+	HMACKeys     []string // secrets for HMAC-SHA256 signed requests, more than one to rotate
+	CertSubjects []string // mTLS client certificate subjects, e.g. "CN=svc,O=Example", or common names
+	// Permissions by keyspace, or "*" for all keyspaces
+	Permissions  map[string][]Permission
+	perms        map[string]map[Permission]bool
+	metricsLabel string
+}
+
+// Validate checks the config and builds the credential lookups
+func (a *Auth) Validate() error {
+	if a.MaxSkew <= 0 {
+		a.MaxSkew = DefaultMaxSkew
+	}
+	a.apiKeys = make(map[[sha256.Size]byte]*Principal)
+	a.subjects = make(map[string]*Principal)
+	a.hmacKeys = false
+	for name, p := range a.Principals {
+		if p == nil {
+			return fmt.Errorf("principal %s: missing config", name)
+		}
+		p.Name, p.metricsLabel = name, name
+		for _, key := range p.APIKeys {
+			if key == "" {
+				return fmt.Errorf("principal %s: empty API key", name)
+			}
+			h := sha256.Sum256([]byte(key))
+			if other, ok := a.apiKeys[h]; ok {
+				return fmt.Errorf("principal %s: duplicate API key, also used by %s", name, other)
+			}
+			a.apiKeys[h] = p
+		}
+		a.hmacKeys = a.hmacKeys || len(p.HMACKeys) > 0
+		for _, subject := range p.CertSubjects {
+			if other, ok := a.subjects[subject]; ok {
+				return fmt.Errorf("principal %s: duplicate cert subject %s, also used by %s", name, subject, other)
+			}
+			a.subjects[subject] = p
+		}
+		if err := p.grant(p.Permissions); err != nil {
+			return fmt.Errorf("principal %s: %w", name, err)
+		}
+	}
+	if err := a.JWT.validate(); err != nil {
+		return err
+	}
+	if a.Enabled && len(a.Principals) == 0 && !a.JWT.Enabled {
+		return errors.New("auth is enabled without principals or JWT")
+	}
+	return nil
+}
+
+// CertOptional reports whether requests can authenticate without a TLS client certificate, by API
+// key, HMAC signature or JWT. Without Enabled all requests are let through, so certs are required.
+func (a *Auth) CertOptional() bool {
+	return a.Enabled && (len(a.apiKeys) > 0 || a.hmacKeys || a.JWT.Enabled)
+}
+
+// grant sets the permissions of the principal
+func (p *Principal) grant(permissions map[string][]Permission) error {
+	p.perms = make(map[string]map[Permission]bool, len(permissions))
+	for keyspace, perms := range permissions {
+		p.perms[keyspace] = make(map[Permission]bool, len(perms))
+		for _, perm := range perms {
+			switch perm {
+			case Read, Write, Delete, Inc:
+				p.perms[keyspace][perm] = true
+			default:
+				return fmt.Errorf("invalid permission %q on keyspace %s", perm, keyspace)
+			}
+		}
+	}
+	return nil
+}
+
+// Authenticate returns the principal of the request. Credentials are tried in order of
+// X-Api-Key header, bearer JWT or HMAC signature in Authorization header, then TLS client
+// certificate.
+// Errors wrap ErrUnauthenticated, except for failure to read the body of a signed request.
+func (a *Auth) Authenticate(r *http.Request) (*Principal, error) {
+	if key := r.Header.Get(HeaderAPIKey); key != "" {
+		if p, ok := a.apiKeys[sha256.Sum256([]byte(key))]; ok {
+			return p, nil
+		}
+		return nil, fmt.Errorf("%w: invalid API key", ErrUnauthenticated)
+	}
+	if _, ok := bearerToken(r); ok && a.JWT.Enabled {
+		return a.JWT.authenticateJWT(r)
+	}
+	if _, ok := hmacCredential(r); ok {
+		return a.authenticateHMAC(r)
+	}
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		cert := r.TLS.VerifiedChains[0][0]
+		if p, ok := a.subjects[cert.Subject.String()]; ok {
+			return p, nil
+		}
+		if p, ok := a.subjects[cert.Subject.CommonName]; ok {
+			return p, nil
+		}
+		return nil, fmt.Errorf("%w: unknown cert subject %s", ErrUnauthenticated, cert.Subject)
+	}
+	return nil, ErrNoCredentials
+}
+
+// Allowed reports whether the principal has perm on keyspace
+func (p *Principal) Allowed(keyspace string, perm Permission) bool {
+	return p != nil && (p.perms[keyspace][perm] || p.perms[AllKeyspaces][perm])
+}
+
+// MetricsLabel returns the principal as a metrics label value, with bounded cardinality
+func (p *Principal) MetricsLabel() string {
+	if p == nil {
+		return ""
+	}
+	return p.metricsLabel
+}
+
+func (p *Principal) String() string {
+	if p == nil {
+		return ""
+	}
+	return p.Name
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx with the principal
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the principal in ctx, nil if there is none
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(contextKey{}).(*Principal)
+	return p
+}
@@ -0,0 +1,169 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newTestAuth(t *testing.T) *Auth {
+	a := &Auth{
+		Enabled: true,
+		Principals: map[string]*Principal{
+			"reader": {
+				APIKeys:     []string{"readerKey"},
+				Permissions: map[string][]Permission{AllKeyspaces: {Read}},
+			},
+			"writer": {
+				HMACKeys:     []string{"old", "new"},
+				CertSubjects: []string{"CN=writer,O=Example"},
+				Permissions:  map[string][]Permission{"gs": {Read, Write, Delete}, "cnt": {Inc}},
+			},
+		},
+	}
+	if err := a.Validate(); err != nil {
+		t.Fatal("Validate() returned error:", err)
+	}
+	return a
+}
+
+func signedRequest(key, principal string, ts time.Time, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPut, "/v2/gs/abc?x=1", bytes.NewBufferString(body))
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	sig := Sign(key, StringToSign(r.Method, r.RequestURI, timestamp, []byte(body)))
+	r.Header.Set("Authorization", HMACScheme+" "+principal+":"+sig)
+	r.Header.Set(HeaderTimestamp, timestamp)
+	return r
+}
+
+func certRequest(subject pkix.Name) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/v2/gs/abc", nil)
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: subject}}}}
+	return r
+}
+
+func TestAuthenticate(t *testing.T) {
+	a := newTestAuth(t)
+	now := time.Now()
+	apiKey := func(key string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/v2/gs/abc", nil)
+		r.Header.Set(HeaderAPIKey, key)
+		return r
+	}
+
+	tests := []struct {
+		name string
+		r    *http.Request
+		want string
+	}{
+		{"api key", apiKey("readerKey"), "reader"},
+		{"invalid api key", apiKey("bad"), ""},
+		{"hmac", signedRequest("new", "writer", now, "body"), "writer"},
+		{"hmac old key", signedRequest("old", "writer", now, "body"), "writer"},
+		{"hmac wrong key", signedRequest("bad", "writer", now, "body"), ""},
+		{"hmac expired", signedRequest("new", "writer", now.Add(-time.Hour), "body"), ""},
+		{"hmac without keys", signedRequest("new", "reader", now, "body"), ""},
+		{"cert subject", certRequest(pkix.Name{CommonName: "writer", Organization: []string{"Example"}}), "writer"},
+		{"cert unknown", certRequest(pkix.Name{CommonName: "other"}), ""},
+		{"none", httptest.NewRequest(http.MethodGet, "/v2/gs/abc", nil), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := a.Authenticate(tt.r)
+			if tt.want == "" {
+				if !errors.Is(err, ErrUnauthenticated) {
+					t.Errorf("Authenticate() got: %v, %v, want ErrUnauthenticated", p, err)
+				}
+				return
+			}
+			if err != nil || p.Name != tt.want {
+				t.Errorf("Authenticate() got: %v, %v, want: %v", p, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticateHMACBody(t *testing.T) {
+	a := newTestAuth(t)
+	r := signedRequest("new", "writer", time.Now(), "body")
+	if _, err := a.Authenticate(r); err != nil {
+		t.Fatal("Authenticate() returned error:", err)
+	}
+	if b, _ := io.ReadAll(r.Body); string(b) != "body" {
+		t.Errorf("Body got: %q, want: %q", b, "body")
+	}
+
+	r = signedRequest("new", "writer", time.Now(), "body")
+	r.Body = io.NopCloser(bytes.NewBufferString("tampered"))
+	if _, err := a.Authenticate(r); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Authenticate() with tampered body got: %v, want ErrUnauthenticated", err)
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	a := newTestAuth(t)
+	reader, writer := a.Principals["reader"], a.Principals["writer"]
+	tests := []struct {
+		p        *Principal
+		keyspace string
+		perm     Permission
+		want     bool
+	}{
+		{reader, "gs", Read, true},
+		{reader, "any", Read, true},
+		{reader, "gs", Write, false},
+		{writer, "gs", Write, true},
+		{writer, "gs", Inc, false},
+		{writer, "cnt", Inc, true},
+		{writer, "other", Read, false},
+		{nil, "gs", Read, false},
+	}
+	for _, tt := range tests {
+		if got := tt.p.Allowed(tt.keyspace, tt.perm); got != tt.want {
+			t.Errorf("%v.Allowed(%v, %v) got: %v, want: %v", tt.p, tt.keyspace, tt.perm, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		auth *Auth
+	}{
+		{"no principals", &Auth{Enabled: true}},
+		{"invalid permission", &Auth{Principals: map[string]*Principal{
+			"a": {Permissions: map[string][]Permission{"gs": {"admin"}}}}}},
+		{"duplicate api key", &Auth{Principals: map[string]*Principal{
+			"a": {APIKeys: []string{"k"}}, "b": {APIKeys: []string{"k"}}}}},
+		{"empty api key", &Auth{Principals: map[string]*Principal{"a": {APIKeys: []string{""}}}}},
+	}
+	for _, tt := range tests {
+		if err := tt.auth.Validate(); err == nil {
+			t.Errorf("%s: Validate() got nil error, want error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	DefaultScopeClaim = "scope"
+
+	// JWTMetricsLabel is the metrics label of JWT subjects not listed in MetricsSubjects
+	JWTMetricsLabel = "jwt"
+)
+
+// JWT holds the settings to authenticate bearer JWTs, signed with RS256, ES256 or EdDSA. Keys are
+// loaded on Validate, so rotated key files are picked up on config reload.
+type JWT struct {
+	Enabled    bool
+	JWKSFiles  []string      // JSON Web Key Set files
+	KeyFiles   []string      // PEM encoded public keys or certificates
+	Audience   string        // required aud claim, not checked if empty
+	Issuer     string        // required iss claim, not checked if empty
+	Leeway     time.Duration // allowed clock skew for exp and nbf, unit in ns
+	ScopeClaim string        // claim holding the scopes, space separated or an array, default "scope"
+	// Permissions by keyspace granted by each scope, or "*" for all keyspaces
+	Scopes map[string]map[string][]Permission
+	// Subjects used as is for the metrics label, others are counted as "jwt" to bound cardinality
+	MetricsSubjects []string
+	keys            map[string]any // by kid
+	keySet          jwt.VerificationKeySet
+	scopes          map[string]*Principal
+	metricsSubjects map[string]bool
+	parser          *jwt.Parser
+}
+
+// validate loads the keys and checks the scopes
+func (j *JWT) validate() error {
+	if !j.Enabled {
+		return nil
+	}
+	if j.ScopeClaim == "" {
+		j.ScopeClaim = DefaultScopeClaim
+	}
+
+	j.keys = make(map[string]any)
+	j.keySet = jwt.VerificationKeySet{}
+	for _, file := range j.JWKSFiles {
+		if err := j.loadJWKS(file); err != nil {
+			return fmt.Errorf("JWKS file %s: %w", file, err)
+		}
+	}
+	for _, file := range j.KeyFiles {
+		if err := j.loadPEM(file); err != nil {
+			return fmt.Errorf("key file %s: %w", file, err)
+		}
+	}
+	if len(j.keySet.Keys) == 0 {
+		return errors.New("JWT is enabled without keys")
+	}
+
+	j.scopes = make(map[string]*Principal, len(j.Scopes))
+	for scope, perms := range j.Scopes {
+		p := &Principal{Name: scope}
+		if err := p.grant(perms); err != nil {
+			return fmt.Errorf("JWT scope %s: %w", scope, err)
+		}
+		j.scopes[scope] = p
+	}
+	j.metricsSubjects = make(map[string]bool, len(j.MetricsSubjects))
+	for _, subject := range j.MetricsSubjects {
+		j.metricsSubjects[subject] = true
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "ES256", "EdDSA"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(j.Leeway),
+	}
+	if j.Audience != "" {
+		opts = append(opts, jwt.WithAudience(j.Audience))
+	}
+	if j.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(j.Issuer))
+	}
+	j.parser = jwt.NewParser(opts...)
+	return nil
+}
+
+// bearerToken returns the bearer token of the request
+func bearerToken(r *http.Request) (string, bool) {
+	return strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// authenticateJWT verifies a bearer JWT and returns its subject as principal, with the
+// permissions granted by its scopes
+func (j *JWT) authenticateJWT(r *http.Request) (*Principal, error) {
+	token, _ := bearerToken(r)
+	claims := jwt.MapClaims{}
+	_, err := j.parser.ParseWithClaims(token, claims, j.keyFunc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+	}
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, fmt.Errorf("%w: JWT without sub", ErrUnauthenticated)
+	}
+
+	p := &Principal{Name: subject, metricsLabel: JWTMetricsLabel, perms: make(map[string]map[Permission]bool)}
+	if j.metricsSubjects[subject] {
+		p.metricsLabel = subject
+	}
+	for _, scope := range tokenScopes(claims[j.ScopeClaim]) {
+		granted, ok := j.scopes[scope]
+		if !ok {
+			continue
+		}
+		for keyspace, perms := range granted.perms {
+			if p.perms[keyspace] == nil {
+				p.perms[keyspace] = make(map[Permission]bool, len(perms))
+			}
+			for perm := range perms {
+				p.perms[keyspace][perm] = true
+			}
+		}
+	}
+	return p, nil
+}
+
+// keyFunc returns the key with the kid of the token, or all keys if the token has no kid
+func (j *JWT) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return j.keySet, nil
+	}
+	key, ok := j.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %s", kid)
+	}
+	return key, nil
+}
+
+// tokenScopes returns the scopes of a claim, either a space separated string or an array
+func tokenScopes(claim any) []string {
+	switch v := claim.(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		scopes := make([]string, 0, len(v))
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				scopes = append(scopes, s)
+			}
+		}
+		return scopes
+	}
+	return nil
+}
+
+func (j *JWT) addKey(kid string, key any) {
+	if kid != "" {
+		j.keys[kid] = key
+	}
+	j.keySet.Keys = append(j.keySet.Keys, key)
+}
+
+// loadJWKS loads the RSA, EC and Ed25519 keys of a JSON Web Key Set
+func (j *JWT) loadJWKS(file string) error {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err = json.Unmarshal(b, &jwks); err != nil {
+		return err
+	}
+
+	decode := base64.RawURLEncoding.DecodeString
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		var key any
+		switch k.Kty {
+		case "RSA":
+			n, err1 := decode(k.N)
+			e, err2 := decode(k.E)
+			if err = errors.Join(err1, err2); err != nil {
+				return fmt.Errorf("kid %s: %w", k.Kid, err)
+			}
+			key = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			if k.Crv != "P-256" {
+				return fmt.Errorf("kid %s: unsupported crv %s", k.Kid, k.Crv)
+			}
+			x, err1 := decode(k.X)
+			y, err2 := decode(k.Y)
+			if err = errors.Join(err1, err2); err != nil {
+				return fmt.Errorf("kid %s: %w", k.Kid, err)
+			}
+			key = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		case "OKP":
+			x, err := decode(k.X)
+			if err != nil || k.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+				return fmt.Errorf("kid %s: invalid Ed25519 key", k.Kid)
+			}
+			key = ed25519.PublicKey(x)
+		default:
+			return fmt.Errorf("kid %s: unsupported kty %s", k.Kid, k.Kty)
+		}
+		j.addKey(k.Kid, key)
+	}
+	return nil
+}
+
+// loadPEM loads the public keys and certificates of a PEM file
+func (j *JWT) loadPEM(file string) error {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var found bool
+	for block, rest := pem.Decode(b); block != nil; block, rest = pem.Decode(rest) {
+		var key any
+		switch block.Type {
+		case "PUBLIC KEY":
+			if key, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+				return err
+			}
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return err
+			}
+			key = cert.PublicKey
+		default:
+			continue
+		}
+		j.addKey("", key)
+		found = true
+	}
+	if !found {
+		return errors.New("no public key found")
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type testKeys struct {
+	rsa     *rsa.PrivateKey
+	ec      *ecdsa.PrivateKey
+	ed      ed25519.PrivateKey
+	jwks    string
+	pemFile string
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	dir := t.TempDir()
+	k := &testKeys{jwks: filepath.Join(dir, "jwks.json"), pemFile: filepath.Join(dir, "key.pem")}
+	var err error
+	if k.rsa, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		t.Fatal(err)
+	}
+	if k.ec, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	if _, k.ed, err = ed25519.GenerateKey(rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+
+	enc := base64.RawURLEncoding.EncodeToString
+	jwks := map[string]any{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa", "use": "sig", "n": enc(k.rsa.N.Bytes()),
+			"e": enc(big.NewInt(int64(k.rsa.E)).Bytes())},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": enc(k.ec.X.Bytes()), "y": enc(k.ec.Y.Bytes())},
+	}}
+	b, _ := json.Marshal(jwks)
+	if err = os.WriteFile(k.jwks, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(k.ed.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(k.pemFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func (k *testKeys) sign(t *testing.T, method jwt.SigningMethod, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	var key any
+	switch method {
+	case jwt.SigningMethodRS256:
+		key = k.rsa
+	case jwt.SigningMethodES256:
+		key = k.ec
+	default:
+		key = k.ed
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestAuthenticateJWT(t *testing.T) {
+	k := newTestKeys(t)
+	a := &Auth{
+		Enabled: true,
+		JWT: JWT{
+			Enabled:   true,
+			JWKSFiles: []string{k.jwks},
+			KeyFiles:  []string{k.pemFile},
+			Audience:  "xdas",
+			Issuer:    "gateway",
+			Scopes: map[string]map[string][]Permission{
+				"xdas.read":     {AllKeyspaces: {Read}},
+				"xdas.gs.write": {"gs": {Write}},
+			},
+			MetricsSubjects: []string{"known"},
+		},
+	}
+	if err := a.Validate(); err != nil {
+		t.Fatal("Validate() returned error:", err)
+	}
+
+	exp := time.Now().Add(time.Hour).Unix()
+	claims := func(sub string, scope any) jwt.MapClaims {
+		return jwt.MapClaims{"sub": sub, "aud": "xdas", "iss": "gateway", "exp": exp, "scope": scope}
+	}
+	tests := []struct {
+		name   string
+		token  string
+		want   string
+		label  string
+		read   bool
+		write  bool
+		noAuth bool
+	}{
+		{"RS256", k.sign(t, jwt.SigningMethodRS256, "rsa", claims("svc", "xdas.read xdas.gs.write")),
+			"svc", JWTMetricsLabel, true, true, false},
+		{"ES256 scope array", k.sign(t, jwt.SigningMethodES256, "ec", claims("known", []string{"xdas.read"})),
+			"known", "known", true, false, false},
+		{"EdDSA without kid", k.sign(t, jwt.SigningMethodEdDSA, "", claims("svc", "xdas.gs.write")),
+			"svc", JWTMetricsLabel, false, true, false},
+		{"unknown kid", k.sign(t, jwt.SigningMethodRS256, "other", claims("svc", "xdas.read")),
+			"", "", false, false, true},
+		{"wrong audience", k.sign(t, jwt.SigningMethodRS256, "rsa",
+			jwt.MapClaims{"sub": "svc", "aud": "other", "iss": "gateway", "exp": exp}), "", "", false, false, true},
+		{"wrong issuer", k.sign(t, jwt.SigningMethodRS256, "rsa",
+			jwt.MapClaims{"sub": "svc", "aud": "xdas", "iss": "other", "exp": exp}), "", "", false, false, true},
+		{"expired", k.sign(t, jwt.SigningMethodRS256, "rsa",
+			jwt.MapClaims{"sub": "svc", "aud": "xdas", "iss": "gateway", "exp": time.Now().Add(-time.Hour).Unix()}),
+			"", "", false, false, true},
+		{"no exp", k.sign(t, jwt.SigningMethodRS256, "rsa",
+			jwt.MapClaims{"sub": "svc", "aud": "xdas", "iss": "gateway"}), "", "", false, false, true},
+		{"key of other kid", k.sign(t, jwt.SigningMethodES256, "rsa", claims("svc", "xdas.read")),
+			"", "", false, false, true},
+		{"malformed", "abc", "", "", false, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v2/gs/abc", nil)
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+			p, err := a.Authenticate(r)
+			if tt.noAuth {
+				if !errors.Is(err, ErrUnauthenticated) {
+					t.Errorf("Authenticate() got: %v, %v, want ErrUnauthenticated", p, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("Authenticate() returned error:", err)
+			}
+			if p.Name != tt.want || p.MetricsLabel() != tt.label {
+				t.Errorf("Authenticate() got: %v/%v, want: %v/%v", p.Name, p.MetricsLabel(), tt.want, tt.label)
+			}
+			if p.Allowed("gs", Read) != tt.read || p.Allowed("gs", Write) != tt.write {
+				t.Errorf("Allowed() got read: %v, write: %v, want read: %v, write: %v", p.Allowed("gs", Read),
+					p.Allowed("gs", Write), tt.read, tt.write)
+			}
+		})
+	}
+}
+
+func TestValidateJWT(t *testing.T) {
+	k := newTestKeys(t)
+	tests := []struct {
+		name string
+		jwt  JWT
+	}{
+		{"no keys", JWT{Enabled: true}},
+		{"missing file", JWT{Enabled: true, JWKSFiles: []string{"missing.json"}}},
+		{"invalid scope permission", JWT{Enabled: true, KeyFiles: []string{k.pemFile},
+			Scopes: map[string]map[string][]Permission{"s": {"gs": {"admin"}}}}},
+	}
+	for _, tt := range tests {
+		a := &Auth{Enabled: true, JWT: tt.jwt}
+		if err := a.Validate(); err == nil {
+			t.Errorf("%s: Validate() got nil error, want error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"xdas/internal/config"
+)
+
+// newTestCert returns a cert for subject signed by parent, self-signed if parent is nil
+func newTestCert(t *testing.T, subject pkix.Name, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      subject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := tmpl, any(key)
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid, tmpl.KeyUsage = true, true, x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ := x509.ParseCertificate(der)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func writePEM(t *testing.T, file, typ string, der []byte) {
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestAuthenticateServerTLS checks that a server verifying client certs requires them, unless Auth
+// lets clients authenticate without one
+func TestAuthenticateServerTLS(t *testing.T) {
+	ca := newTestCert(t, pkix.Name{CommonName: "ca"}, nil)
+	server := newTestCert(t, pkix.Name{CommonName: "server"}, &ca)
+	client := newTestCert(t, pkix.Name{CommonName: "writer", Organization: []string{"Example"}}, &ca)
+
+	dir := t.TempDir()
+	conf := &config.TLS{
+		CertFile: filepath.Join(dir, "cert.pem"),
+		KeyFile:  filepath.Join(dir, "key.pem"),
+		CaFile:   filepath.Join(dir, "ca.pem"),
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(server.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, conf.CertFile, "CERTIFICATE", server.Certificate[0])
+	writePEM(t, conf.KeyFile, "PRIVATE KEY", keyDER)
+	writePEM(t, conf.CaFile, "CERTIFICATE", ca.Certificate[0])
+	if err = conf.Validate(); err != nil {
+		t.Fatal("Validate() returned error:", err)
+	}
+
+	withKeys := newTestAuth(t)
+	disabled := newTestAuth(t)
+	disabled.Enabled = false
+	certOnly := &Auth{Enabled: true, Principals: map[string]*Principal{
+		"writer": {CertSubjects: []string{"CN=writer,O=Example"}},
+	}}
+	if err = certOnly.Validate(); err != nil {
+		t.Fatal("Validate() returned error:", err)
+	}
+
+	a := withKeys // the Auth of the handshake and the handler, set by each test
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := a.Authenticate(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		io.WriteString(w, p.Name)
+	}))
+	srv.TLS = conf.GetServerTLS(func() bool { return a.CertOptional() })
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+	tests := []struct {
+		name       string
+		auth       *Auth
+		apiKey     string
+		certs      []tls.Certificate
+		wantStatus int // 0 if the handshake fails
+		want       string
+	}{
+		{"api key without cert", withKeys, "readerKey", nil, http.StatusOK, "reader"},
+		{"cert", withKeys, "", []tls.Certificate{client}, http.StatusOK, "writer"},
+		{"no credentials", withKeys, "", nil, http.StatusUnauthorized, ""},
+		{"auth disabled without cert", disabled, "readerKey", nil, 0, ""},
+		{"auth disabled with cert", disabled, "", []tls.Certificate{client}, http.StatusOK, "writer"},
+		{"cert principals only without cert", certOnly, "readerKey", nil, 0, ""},
+		{"cert principals only with cert", certOnly, "", []tls.Certificate{client}, http.StatusOK, "writer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a = tt.auth
+			c := &http.Client{Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: tt.certs},
+			}}
+			r, _ := http.NewRequest(http.MethodGet, srv.URL+"/v2/gs/abc", nil)
+			if tt.apiKey != "" {
+				r.Header.Set(HeaderAPIKey, tt.apiKey)
+			}
+			resp, err := c.Do(r)
+			if tt.wantStatus == 0 {
+				if err == nil {
+					resp.Body.Close()
+					t.Errorf("Do() got: %d, want handshake error", resp.StatusCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("Do() returned error:", err)
+			}
+			defer resp.Body.Close()
+			b, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus || string(b) != tt.want {
+				t.Errorf("got: %d %q, want: %d %q", resp.StatusCode, b, tt.wantStatus, tt.want)
+			}
+		})
+	}
+}
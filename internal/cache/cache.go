@@ -0,0 +1,183 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package cache is a bounded in-process LRU cache of hot keys in front of the store. It keeps the
+// converted output of each key per output magicByte for a short TTL, so repeated reads skip the
+// store, decryption and conversion.
+package cache
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+	"xdas/internal/magicbyte"
+)
+
+const (
+	DefaultMaxEntries   = 10000
+	DefaultMaxValueSize = 64 << 10
+	DefaultTTL          = time.Second
+)
+
+// Channel is the Redis pubsub channel of invalidations, messages are the Redis keys written
+const Channel = "xdas:invalidate"
+
+// Cache holds the settings and entries of a keyspace cache. Each key has an entry holding its
+// values per output magicByte, entries beyond MaxEntries are evicted least recently used first.
+type Cache struct {
+	Enabled      bool
+	MaxEntries   int           // max keys kept, default 10000
+	MaxValueSize int           // values larger than this are not kept, default 64KiB
+	TTL          time.Duration // how long a value is kept, unit in ns, default 1s
+	Invalidate   bool          // publish writes to and drop keys written by other pods on Channel
+	mu           sync.Mutex
+	lru          *list.List // of *entry, most recently used first
+	entries      map[string]*list.Element
+	gen          uint64 // incremented by Delete
+	now          func() time.Time
+}
+
+// Value is a value converted to an output format
+type Value struct {
+	MagicByte magicbyte.MagicByte
+	Data      []byte
+}
+
+type entry struct {
+	key    string
+	values map[byte]item // by output magicByte
+}
+
+type item struct {
+	Value
+	expires time.Time
+}
+
+// Validate checks the config and sets defaults
+func (c *Cache) Validate() error {
+	if c.MaxEntries < 0 || c.MaxValueSize < 0 || c.TTL < 0 {
+		return errors.New("MaxEntries, MaxValueSize and TTL must not be negative")
+	}
+	if c.MaxEntries == 0 {
+		c.MaxEntries = DefaultMaxEntries
+	}
+	if c.MaxValueSize == 0 {
+		c.MaxValueSize = DefaultMaxValueSize
+	}
+	if c.TTL == 0 {
+		c.TTL = DefaultTTL
+	}
+	c.lru = list.New()
+	c.entries = make(map[string]*list.Element)
+	c.now = time.Now
+	return nil
+}
+
+// Gen returns the generation of the cache, to be passed to Add. Take it before reading the store,
+// so a value read before a concurrent write is not added after the write has deleted the key.
+func (c *Cache) Gen() uint64 {
+	if !c.Enabled {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+// Get returns the value of key converted to out, false if it isn't cached or has expired
+func (c *Cache) Get(key string, out magicbyte.MagicByte) (Value, bool) {
+	if !c.Enabled {
+		return Value{}, false
+	}
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return Value{}, false
+	}
+	e := elem.Value.(*entry)
+	it, ok := e.values[out.Get()]
+	if !ok {
+		return Value{}, false
+	}
+	if !now.Before(it.expires) {
+		delete(e.values, out.Get())
+		if len(e.values) == 0 {
+			c.remove(elem)
+		}
+		return Value{}, false
+	}
+	c.lru.MoveToFront(elem)
+	return it.Value, true
+}
+
+// Add keeps the value of key converted to out, unless the key was deleted since gen was taken
+// from Gen. v.Data must not be modified afterwards.
+func (c *Cache) Add(key string, out magicbyte.MagicByte, gen uint64, v Value) {
+	if !c.Enabled || len(v.Data) > c.MaxValueSize {
+		return
+	}
+	now := c.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.gen {
+		return
+	}
+	elem, ok := c.entries[key]
+	if ok {
+		c.lru.MoveToFront(elem)
+	} else {
+		if c.lru.Len() >= c.MaxEntries {
+			c.remove(c.lru.Back())
+		}
+		elem = c.lru.PushFront(&entry{key: key, values: make(map[byte]item, 1)})
+		c.entries[key] = elem
+	}
+	elem.Value.(*entry).values[out.Get()] = item{Value: v, expires: now.Add(c.TTL)}
+}
+
+// Delete drops all the values of key
+func (c *Cache) Delete(key string) {
+	if !c.Enabled {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+}
+
+// Len returns the number of keys cached
+func (c *Cache) Len() int {
+	if !c.Enabled {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+func (c *Cache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*entry).key)
+}
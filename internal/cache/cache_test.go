@@ -0,0 +1,122 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"bytes"
+	"testing"
+	"time"
+	"xdas/internal/magicbyte"
+)
+
+var (
+	jsonMB  = magicbyte.New("", "application/json", 0)
+	protoMB = magicbyte.New("", "application/x-protobuf", 0)
+)
+
+func newTestCache(t *testing.T, maxEntries int) (*Cache, *time.Time) {
+	c := &Cache{Enabled: true, MaxEntries: maxEntries, MaxValueSize: 8}
+	if err := c.Validate(); err != nil {
+		t.Fatal("Validate() returned error:", err)
+	}
+	now := time.Unix(1700000000, 0)
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func value(data string) Value {
+	return Value{MagicByte: jsonMB, Data: []byte(data)}
+}
+
+func TestGetAdd(t *testing.T) {
+	c, now := newTestCache(t, 10)
+	get := func(key string, out magicbyte.MagicByte, want string, wantOK bool) {
+		t.Helper()
+		v, ok := c.Get(key, out)
+		if ok != wantOK || !bytes.Equal(v.Data, []byte(want)) {
+			t.Errorf("Get(%v, %v) got: %q, %v, want: %q, %v", key, out.Get(), v.Data, ok, want, wantOK)
+		}
+	}
+
+	get("a", jsonMB, "", false)
+	c.Add("a", jsonMB, c.Gen(), value("json"))
+	c.Add("a", protoMB, c.Gen(), value("proto"))
+	get("a", jsonMB, "json", true)
+	get("a", protoMB, "proto", true)
+
+	c.Add("b", jsonMB, c.Gen(), value("too large"))
+	get("b", jsonMB, "", false)
+
+	*now = now.Add(DefaultTTL)
+	get("a", jsonMB, "", false)
+	if got := c.Len(); got != 1 {
+		t.Errorf("Len() got: %v, want: 1", got)
+	}
+	get("a", protoMB, "", false)
+	if got := c.Len(); got != 0 {
+		t.Errorf("Len() got: %v, want: 0", got)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	c, _ := newTestCache(t, 10)
+	c.Add("a", jsonMB, c.Gen(), value("a"))
+	c.Add("a", protoMB, c.Gen(), value("a"))
+	gen := c.Gen()
+	c.Delete("a")
+	if _, ok := c.Get("a", jsonMB); ok {
+		t.Error("Get() after Delete() found the key")
+	}
+	if _, ok := c.Get("a", protoMB); ok {
+		t.Error("Get() after Delete() found the key")
+	}
+
+	// read before the delete, added after it
+	c.Add("a", jsonMB, gen, value("stale"))
+	if _, ok := c.Get("a", jsonMB); ok {
+		t.Error("Add() with a gen before Delete() added the value")
+	}
+}
+
+func TestEvict(t *testing.T) {
+	c, _ := newTestCache(t, 2)
+	c.Add("a", jsonMB, c.Gen(), value("a"))
+	c.Add("b", jsonMB, c.Gen(), value("b"))
+	c.Get("a", jsonMB) // b is now the least recently used
+	c.Add("c", jsonMB, c.Gen(), value("c"))
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := c.Get(key, jsonMB); ok != want {
+			t.Errorf("Get(%v) got: %v, want: %v", key, ok, want)
+		}
+	}
+	if got := c.Len(); got != 2 {
+		t.Errorf("Len() got: %v, want: 2", got)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	c := new(Cache)
+	if err := c.Validate(); err != nil {
+		t.Fatal("Validate() returned error:", err)
+	}
+	c.Add("a", jsonMB, c.Gen(), value("a"))
+	if _, ok := c.Get("a", jsonMB); ok {
+		t.Error("Get() of disabled cache found the key")
+	}
+	c.Delete("a")
+}
@@ -55,6 +55,7 @@ func NewHClient() *HClientConfig {
 }
 
 func (h *HClientConfig) Validate() error {
+	h.TLS.applyEnv(EnvPrefix + "HCLIENT_TLS")
 	if err := h.TLS.Validate(); err != nil {
 		return err
 	}
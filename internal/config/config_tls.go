@@ -23,6 +23,8 @@ import (
 	"os"
 )
 
+var errNoClientCert = errors.New("tls: client didn't provide a certificate")
+
 // TLS holds config for TLS
 type TLS struct {
 	CertFile string // Client or Server cert
@@ -43,15 +45,28 @@ func (t *TLS) GetClientTLS() *tls.Config {
 }
 
 // GetServerTLS return tls.config for use by server. Invoke Validate before calling this.
-func (t *TLS) GetServerTLS() *tls.Config {
+// If CaFile is set, client certs are required and verified against it. certOptional, if not nil,
+// is called on each handshake, when it returns true client certs are only verified if given, so
+// that clients can authenticate by other means.
+func (t *TLS) GetServerTLS(certOptional func() bool) *tls.Config {
 	if t.cert == nil {
 		return nil
 	}
 
 	c := &tls.Config{Certificates: t.cert}
 	if t.ca != nil {
-		c.ClientAuth = tls.RequireAndVerifyClientCert
 		c.ClientCAs = t.ca
+		c.ClientAuth = tls.RequireAndVerifyClientCert
+		if certOptional != nil {
+			// certs given are still verified, missing ones are checked after the handshake
+			c.ClientAuth = tls.VerifyClientCertIfGiven
+			c.VerifyConnection = func(cs tls.ConnectionState) error {
+				if len(cs.PeerCertificates) == 0 && !certOptional() {
+					return errNoClientCert
+				}
+				return nil
+			}
+		}
 	}
 	return c
 }
@@ -63,6 +78,14 @@ func (t *TLS) Set(orig *tls.Config) {
 	orig.InsecureSkipVerify = t.Insecure
 }
 
+// applyEnv overrides the file paths with environment variables <prefix>_CERT_FILE,
+// <prefix>_KEY_FILE and <prefix>_CA_FILE, if set
+func (t *TLS) applyEnv(prefix string) {
+	envOverride(prefix+"_CERT_FILE", &t.CertFile)
+	envOverride(prefix+"_KEY_FILE", &t.KeyFile)
+	envOverride(prefix+"_CA_FILE", &t.CaFile)
+}
+
 func (t *TLS) Validate() error {
 	if t.CertFile != "" {
 		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
@@ -51,5 +51,6 @@ func (w *WebConfig) Validate() error {
 	if w.Server.Addr == "" {
 		return ErrNoWebAddr
 	}
-	return nil
+	w.TLS.applyEnv(EnvPrefix + "WEB_TLS")
+	return w.TLS.Validate()
 }
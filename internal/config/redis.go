@@ -18,22 +18,33 @@ package config
 
 import (
 	"errors"
+	"log/slog"
+	"strings"
 
-	"github.com/go-redis/redis/v7"
+	"github.com/redis/go-redis/v9"
 )
 
 var (
 	ErrNoRedisAddr    = errors.New("missing Redis addr")
 	ErrInvalidEncrypt = errors.New("invalid encryption")
 	ErrNoEncryptKey   = errors.New("missing EncryptionKey")
+	ErrInvalidProto   = errors.New("invalid Protocol, must be 2 or 3")
 )
 
-// RedisConfig holds config for Redis and encryption
+// RedisConfig holds config for Redis and encryption.
+// ClientConfig.Password can be overridden by XX_REDIS_PASSWORD, XX_REDIS_PASSWORD_FILE or
+// PasswordFile, EncryptionKey by XX_REDIS_ENCRYPTION_KEY, XX_REDIS_ENCRYPTION_KEY_FILE or
+// EncryptionKeyFile, in that order of precedence. Multiple keys are separated by comma or newline.
 type RedisConfig struct {
-	// ClientConfig uses https://godoc.org/github.com/go-redis/redis#NewUniversalClient
-	ClientConfig  *redis.UniversalOptions
-	EncryptionKey []string
-	Encryption    int
+	// ClientConfig uses https://pkg.go.dev/github.com/redis/go-redis/v9#NewUniversalClient.
+	// ContextTimeoutEnabled is always set, so calls end with the context of the request.
+	ClientConfig        *redis.UniversalOptions
+	PasswordFile        string
+	EncryptionKey       []string
+	EncryptionKeyFile   string
+	Encryption          int
+	passwordSource      string
+	encryptionKeySource string
 }
 
 func NewRedis() *RedisConfig {
@@ -41,9 +52,36 @@ func NewRedis() *RedisConfig {
 }
 
 func (c *RedisConfig) Validate() error {
+	password, source, err := resolveSecret(EnvPrefix+"REDIS_PASSWORD", c.PasswordFile, c.ClientConfig.Password)
+	if err != nil {
+		return err
+	}
+	c.ClientConfig.Password, c.passwordSource = password, source
+
 	if len(c.ClientConfig.Addrs) < 1 {
 		return ErrNoRedisAddr
 	}
+	switch c.ClientConfig.Protocol {
+	case 0:
+		c.ClientConfig.Protocol = 3
+	case 2, 3:
+	default:
+		return ErrInvalidProto
+	}
+	c.ClientConfig.ContextTimeoutEnabled = true
+	return c.ValidateEncryption()
+}
+
+// ValidateEncryption only checks the encryption config, for stores other than Redis
+func (c *RedisConfig) ValidateEncryption() error {
+	key, source, err := resolveSecret(EnvPrefix+"REDIS_ENCRYPTION_KEY", c.EncryptionKeyFile,
+		strings.Join(c.EncryptionKey, ","))
+	if err != nil {
+		return err
+	}
+	c.EncryptionKey = strings.FieldsFunc(key, func(r rune) bool { return r == ',' || r == '\n' || r == '\r' })
+	c.encryptionKeySource = source
+
 	if c.Encryption < 0 || c.Encryption > 1 {
 		return ErrInvalidEncrypt
 	}
@@ -52,3 +90,19 @@ func (c *RedisConfig) Validate() error {
 	}
 	return nil
 }
+
+// PasswordSource returns where the Redis password comes from, empty if not set
+func (c *RedisConfig) PasswordSource() string { return c.passwordSource }
+
+// EncryptionKeySource returns where the encryption key comes from, empty if not set
+func (c *RedisConfig) EncryptionKeySource() string { return c.encryptionKeySource }
+
+// LogValue implements slog.LogValuer, secrets are not logged, only their source
+func (c *RedisConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Any("addrs", c.ClientConfig.Addrs),
+		slog.String("password", c.passwordSource),
+		slog.String("encryptionKey", c.encryptionKeySource),
+		slog.Int("encryption", c.Encryption),
+	)
+}
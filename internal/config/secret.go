@@ -0,0 +1,73 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EnvPrefix is the prefix of environment variables that override config
+const EnvPrefix = "XX_"
+
+// Source of a secret
+const (
+	SourceEnv        = "env"
+	SourceEnvFile    = "env file"
+	SourceConfigFile = "config file"
+	SourceConfig     = "config"
+)
+
+// resolveSecret returns a secret by precedence, from highest to lowest:
+//   - environment variable env
+//   - file named by environment variable env + "_FILE"
+//   - file named by file, from config
+//   - value, from config
+//
+// It also returns the source of the secret, which is safe to log. Surrounding whitespace is
+// removed from files, so mounted secrets with a trailing newline work as is.
+func resolveSecret(env, file, value string) (secret, source string, err error) {
+	if v, ok := os.LookupEnv(env); ok && v != "" {
+		return v, SourceEnv + " " + env, nil
+	}
+	if f := os.Getenv(env + "_FILE"); f != "" {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return "", "", fmt.Errorf("%s_FILE: %w", env, err)
+		}
+		return strings.TrimSpace(string(b)), SourceEnvFile + " " + f, nil
+	}
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return "", "", err
+		}
+		return strings.TrimSpace(string(b)), SourceConfigFile + " " + file, nil
+	}
+	if value != "" {
+		return value, SourceConfig, nil
+	}
+	return "", "", nil
+}
+
+// envOverride sets *s to the value of environment variable env, if it is set
+func envOverride(env string, s *string) {
+	if v, ok := os.LookupEnv(env); ok && v != "" {
+		*s = v
+	}
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveSecret(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, "env")
+	configFile := filepath.Join(dir, "config")
+	os.WriteFile(envFile, []byte("fromEnvFile\n"), 0600)
+	os.WriteFile(configFile, []byte("fromConfigFile\n"), 0600)
+
+	tests := []struct {
+		name       string
+		env        string
+		envFile    string
+		file       string
+		value      string
+		want       string
+		wantSource string
+		wantErr    bool
+	}{
+		{"none", "", "", "", "", "", "", false},
+		{"config", "", "", "", "fromConfig", "fromConfig", SourceConfig, false},
+		{"config file", "", "", configFile, "fromConfig", "fromConfigFile", SourceConfigFile + " " + configFile, false},
+		{"env file", "", envFile, configFile, "fromConfig", "fromEnvFile", SourceEnvFile + " " + envFile, false},
+		{"env", "fromEnv", envFile, configFile, "fromConfig", "fromEnv", SourceEnv + " XX_TEST_SECRET", false},
+		{"missing file", "", "", filepath.Join(dir, "missing"), "", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XX_TEST_SECRET", tt.env)
+			t.Setenv("XX_TEST_SECRET_FILE", tt.envFile)
+			got, source, err := resolveSecret("XX_TEST_SECRET", tt.file, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveSecret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want || source != tt.wantSource {
+				t.Errorf("resolveSecret() got: %q, %q, want: %q, %q", got, source, tt.want, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestRedisValidateEncryptionKeyFile(t *testing.T) {
+	key := "0a0b0c0d0e0f101112131415161718190a0b0c0d0e0f10111213141516171819"
+	file := filepath.Join(t.TempDir(), "key")
+	os.WriteFile(file, []byte(key+"\n"), 0600)
+
+	c := NewRedis()
+	c.ClientConfig.Addrs = []string{"localhost:6379"}
+	c.EncryptionKey = []string{"inline"}
+	c.EncryptionKeyFile = file
+	if err := c.Validate(); err != nil {
+		t.Fatal("Validate() returned error:", err)
+	}
+	if len(c.EncryptionKey) != 1 || c.EncryptionKey[0] != key {
+		t.Errorf("EncryptionKey got: %v, want: [%v]", c.EncryptionKey, key)
+	}
+	if c.EncryptionKeySource() != SourceConfigFile+" "+file {
+		t.Errorf("EncryptionKeySource() got: %v", c.EncryptionKeySource())
+	}
+}
+
+func TestRedisValidateProtocol(t *testing.T) {
+	tests := []struct {
+		protocol int
+		want     int
+		err      error
+	}{
+		{0, 3, nil},
+		{2, 2, nil},
+		{3, 3, nil},
+		{1, 1, ErrInvalidProto},
+	}
+	for _, tt := range tests {
+		c := NewRedis()
+		c.ClientConfig.Addrs = []string{"localhost:6379"}
+		c.ClientConfig.Protocol = tt.protocol
+		c.EncryptionKey = []string{"key"}
+		if err := c.Validate(); err != tt.err {
+			t.Errorf("Validate() with Protocol %v got: %v, want: %v", tt.protocol, err, tt.err)
+			continue
+		}
+		if c.ClientConfig.Protocol != tt.want {
+			t.Errorf("Protocol got: %v, want: %v", c.ClientConfig.Protocol, tt.want)
+		}
+		if tt.err == nil && !c.ClientConfig.ContextTimeoutEnabled {
+			t.Error("ContextTimeoutEnabled is not set")
+		}
+	}
+}
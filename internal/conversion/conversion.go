@@ -17,35 +17,72 @@
 package conversion
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 	"xdas/internal/magicbyte"
 	"xdas/internal/rediscrypto"
+	"xdas/internal/tracing"
 
 	"github.com/klauspost/compress/zstd"
 	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 )
 
+// DefaultMaxDecompressedSize is the limit of decompressed data unless set by SetMaxDecompressedSize
+const DefaultMaxDecompressedSize = 64 << 20
+
+// maxWindowSize limits the memory a zstd frame can ask for, regardless of its content size
+const maxWindowSize = 64 << 20
+
 var (
 	defaultMetrics metricsProvider
+	tracer         = tracing.Tracer("xdas/internal/conversion")
 
-	zstdDec *zstd.Decoder
 	zstdEnc *zstd.Encoder
+	// zstdStreamDec holds single goroutine decoders for streaming with a limit
+	zstdStreamDec = sync.Pool{New: func() any {
+		dec, _ := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxWindow(maxWindowSize))
+		return dec
+	}}
+	limits atomic.Pointer[decompressLimits]
 
 	ErrUnknownKeyspace     = errors.New("unknown keyspace definition")
 	ErrUnknownEncodingType = errors.New("unkown encoding type")
 	ErrUnknownContentType  = errors.New("unknown content-type")
+	ErrCorrupt             = errors.New("corrupt compressed data")
 )
 
+// SizeError is returned when data decompresses to more than Limit bytes
+type SizeError struct {
+	Limit int64
+}
+
+func (e *SizeError) Error() string {
+	return "decompressed size exceeds limit of " + strconv.FormatInt(e.Limit, 10) + " bytes"
+}
+
+type decompressLimits struct {
+	max       int64
+	keyspaces map[string]int64
+}
+
 // pbMessage holds the appropriate data structure for the keyspace
 var pbMessage = map[string]func() proto.Message{}
 
 func init() {
 	defaultMetrics = &noMetrics{}
-	zstdDec, _ = zstd.NewReader(nil)
+	limits.Store(&decompressLimits{max: DefaultMaxDecompressedSize})
 	zstdEnc, _ = zstd.NewWriter(nil, zstd.WithZeroFrames(true))
 }
 
@@ -65,64 +102,101 @@ func Init(promReg prometheus.Registerer, promNamespace string, keyspaces []strin
 	}
 }
 
+// AddKeyspaces adds metrics for keyspaces added after Init
+func AddKeyspaces(keyspaces []string) error {
+	return defaultMetrics.addKeyspaces(keyspaces)
+}
+
+// SetMaxDecompressedSize sets the limit of decompressed data to max, or DefaultMaxDecompressedSize
+// if less than 1, and overrides it for the keyspaces in keyspaces. It is safe to call while
+// converting, to apply a reloaded config.
+func SetMaxDecompressedSize(max int64, keyspaces map[string]int64) {
+	if max < 1 {
+		max = DefaultMaxDecompressedSize
+	}
+	limits.Store(&decompressLimits{max: max, keyspaces: keyspaces})
+}
+
+// MaxDecompressedSize returns the limit of decompressed data for keyspace
+func MaxDecompressedSize(keyspace string) int64 {
+	l := limits.Load()
+	if limit, ok := l.keyspaces[keyspace]; ok {
+		return limit
+	}
+	return l.max
+}
+
 // Convert returns data based on outMagicByte
 func Convert(keyspace string, inMagicByte, outMagicByte magicbyte.MagicByte, inData []byte) (magicbyte.MagicByte, []byte, error) {
+	return ConvertContext(context.Background(), keyspace, inMagicByte, outMagicByte, inData)
+}
+
+// ConvertContext returns data based on outMagicByte, tracing each step as a child span of ctx
+func ConvertContext(ctx context.Context, keyspace string, inMagicByte, outMagicByte magicbyte.MagicByte,
+	inData []byte) (magicbyte.MagicByte, []byte, error) {
 	if outMagicByte.GetCTV() == 0 {
 		outMagicByte = magicbyte.NewMagicByte(outMagicByte.GetCEV(), inMagicByte.GetCTV(),
 			outMagicByte.GetEncryption())
 	}
+	if inMagicByte == outMagicByte {
+		return inMagicByte, inData, nil
+	}
+	ctx, span := tracer.Start(ctx, "convert", trace.WithAttributes(attribute.String("xdas.keyspace", keyspace),
+		attribute.Int("xdas.magic_byte.in", int(inMagicByte.Get())),
+		attribute.Int("xdas.magic_byte.out", int(outMagicByte.Get()))))
+	defer span.End()
 
 	if inMagicByte.GetCTV() != outMagicByte.GetCTV() && outMagicByte.GetCTV() != 0 {
 		// decrypt -> decompress -> unmarshal -> marshal -> compress -> encrypt
-		pb, err := Unpack(keyspace, inMagicByte, inData)
+		pb, err := UnpackContext(ctx, keyspace, inMagicByte, inData)
 		if err != nil {
 			defaultMetrics.incContentTypeFail(keyspace)
-			return inMagicByte, inData, err
+			return inMagicByte, inData, endSpan(span, err)
 		}
-		data, err := Pack(outMagicByte, pb)
+		data, err := pack(ctx, keyspace, outMagicByte, pb)
 		if err != nil {
 			defaultMetrics.incContentTypeFail(keyspace)
-			return inMagicByte, inData, err
+			return inMagicByte, inData, endSpan(span, err)
 		}
 		defaultMetrics.incContentTypeSuc(keyspace)
 		return outMagicByte, data, err
 	}
 	if inMagicByte.GetCEV() != outMagicByte.GetCEV() {
 		// decrypt -> decompress -> compress -> encrypt
-		data, err := Decrypt(inMagicByte.GetEncryption(), inData)
+		data, err := decrypt(ctx, keyspace, inMagicByte.GetEncryption(), inData)
 		if err != nil {
 			defaultMetrics.incContentEncodingFail(keyspace)
-			return inMagicByte, inData, err
+			return inMagicByte, inData, endSpan(span, err)
 		}
-		data, err = Decompress(inMagicByte.GetCEV(), data)
+		data, err = decompress(ctx, keyspace, inMagicByte.GetCEV(), data, MaxDecompressedSize(keyspace))
 		if err != nil {
 			defaultMetrics.incContentEncodingFail(keyspace)
-			return inMagicByte, inData, err
+			return inMagicByte, inData, endSpan(span, err)
 		}
-		data, err = Compress(outMagicByte.GetCEV(), data)
+		data, err = compress(ctx, keyspace, outMagicByte.GetCEV(), data)
 		if err != nil {
 			defaultMetrics.incContentEncodingFail(keyspace)
-			return inMagicByte, inData, err
+			return inMagicByte, inData, endSpan(span, err)
 		}
-		data, err = Encrypt(outMagicByte.GetEncryption(), data)
+		data, err = encrypt(ctx, keyspace, outMagicByte.GetEncryption(), data)
 		if err != nil {
 			defaultMetrics.incContentEncodingFail(keyspace)
-			return inMagicByte, inData, err
+			return inMagicByte, inData, endSpan(span, err)
 		}
 		defaultMetrics.incContentEncodingSuc(keyspace)
 		return outMagicByte, data, err
 	}
 	if inMagicByte.GetEncryption() != outMagicByte.GetEncryption() {
 		// decrypt -> encrypt
-		data, err := Decrypt(inMagicByte.GetEncryption(), inData)
+		data, err := decrypt(ctx, keyspace, inMagicByte.GetEncryption(), inData)
 		if err != nil {
 			defaultMetrics.incEncryptionFail(keyspace)
-			return inMagicByte, inData, err
+			return inMagicByte, inData, endSpan(span, err)
 		}
-		data, err = Encrypt(outMagicByte.GetEncryption(), data)
+		data, err = encrypt(ctx, keyspace, outMagicByte.GetEncryption(), data)
 		if err != nil {
 			defaultMetrics.incEncryptionFail(keyspace)
-			return inMagicByte, inData, err
+			return inMagicByte, inData, endSpan(span, err)
 		}
 		defaultMetrics.incEncryptionSuc(keyspace)
 		return outMagicByte, data, err
@@ -130,39 +204,122 @@ func Convert(keyspace string, inMagicByte, outMagicByte magicbyte.MagicByte, inD
 	return inMagicByte, inData, nil
 }
 
+// HasMessage returns true if a protobuf message is registered for keyspace, which is required
+// to convert between content-types and to validate content
+func HasMessage(keyspace string) bool {
+	_, ok := pbMessage[keyspace]
+	return ok
+}
+
 // Unpack will Decrypt, Decompress and Unmarshal the inData based on inMagicByte and returns a Message
 func Unpack(keyspace string, inMagicByte magicbyte.MagicByte, inData []byte) (proto.Message, error) {
+	return UnpackContext(context.Background(), keyspace, inMagicByte, inData)
+}
+
+// UnpackContext is Unpack tracing each step as a child span of ctx
+func UnpackContext(ctx context.Context, keyspace string, inMagicByte magicbyte.MagicByte, inData []byte) (proto.Message, error) {
 	if newPb, ok := pbMessage[keyspace]; ok {
-		return UnPackByPB(newPb(), inMagicByte, inData)
+		return unpack(ctx, keyspace, newPb(), inMagicByte, inData, MaxDecompressedSize(keyspace))
 	}
 	return nil, ErrUnknownKeyspace
 }
 
 // UnPackByPB will Decrypt, Decompress and Unmarshal the inData based on inMagicByte and returns a Message
 func UnPackByPB(pb proto.Message, inMagicByte magicbyte.MagicByte, inData []byte) (proto.Message, error) {
-	data, err := Decrypt(inMagicByte.GetEncryption(), inData)
+	return unpack(context.Background(), "", pb, inMagicByte, inData, limits.Load().max)
+}
+
+func unpack(ctx context.Context, keyspace string, pb proto.Message, inMagicByte magicbyte.MagicByte, inData []byte,
+	limit int64) (proto.Message, error) {
+	data, err := decrypt(ctx, keyspace, inMagicByte.GetEncryption(), inData)
 	if err != nil {
 		return nil, err
 	}
-	data, err = Decompress(inMagicByte.GetCEV(), data)
+	data, err = decompress(ctx, keyspace, inMagicByte.GetCEV(), data, limit)
 	if err != nil {
 		return nil, err
 	}
-	err = Unmarshal(inMagicByte.GetCTV(), pb, data)
+	_, err = step(ctx, keyspace, "unmarshal", len(data), func() ([]byte, error) {
+		return nil, Unmarshal(inMagicByte.GetCTV(), pb, data)
+	})
 	return pb, err
 }
 
 // Pack will Marshal, Compress and Encrypt the Message based on outMagicByte and returns data
 func Pack(outMagicByte magicbyte.MagicByte, pb proto.Message) ([]byte, error) {
-	data, err := Marshal(outMagicByte.GetCTV(), pb)
+	return pack(context.Background(), "", outMagicByte, pb)
+}
+
+func pack(ctx context.Context, keyspace string, outMagicByte magicbyte.MagicByte, pb proto.Message) ([]byte, error) {
+	data, err := step(ctx, keyspace, "marshal", 0, func() ([]byte, error) {
+		return Marshal(outMagicByte.GetCTV(), pb)
+	})
 	if err != nil {
 		return data, err
 	}
-	data, err = Compress(outMagicByte.GetCEV(), data)
+	data, err = compress(ctx, keyspace, outMagicByte.GetCEV(), data)
 	if err != nil {
 		return data, err
 	}
-	return Encrypt(outMagicByte.GetEncryption(), data)
+	return encrypt(ctx, keyspace, outMagicByte.GetEncryption(), data)
+}
+
+// decrypt, decompress, compress and encrypt trace and time the steps that change the data
+
+func decrypt(ctx context.Context, keyspace string, encryption int, inData []byte) ([]byte, error) {
+	if encryption == 0 {
+		return inData, nil
+	}
+	return step(ctx, keyspace, "decrypt", len(inData), func() ([]byte, error) { return Decrypt(encryption, inData) })
+}
+
+func encrypt(ctx context.Context, keyspace string, encryption int, inData []byte) ([]byte, error) {
+	if encryption == 0 {
+		return inData, nil
+	}
+	return step(ctx, keyspace, "encrypt", len(inData), func() ([]byte, error) { return Encrypt(encryption, inData) })
+}
+
+func decompress(ctx context.Context, keyspace string, cev int, inData []byte, limit int64) ([]byte, error) {
+	if cev == magicbyte.ContentEncodingNone {
+		return inData, nil
+	}
+	return step(ctx, keyspace, "decompress", len(inData), func() ([]byte, error) { return DecompressLimit(cev, inData, limit) })
+}
+
+func compress(ctx context.Context, keyspace string, cev int, inData []byte) ([]byte, error) {
+	if cev == magicbyte.ContentEncodingNone {
+		return inData, nil
+	}
+	return step(ctx, keyspace, "compress", len(inData), func() ([]byte, error) { return Compress(cev, inData) })
+}
+
+// step runs f in a child span of ctx named after the step, with the size of its input and
+// output, and observes its duration
+func step(ctx context.Context, keyspace, name string, size int, f func() ([]byte, error)) ([]byte, error) {
+	_, span := tracer.Start(ctx, name)
+	defer span.End()
+	start := time.Now()
+	out, err := f()
+	defaultMetrics.observeStep(keyspace, name, time.Since(start))
+	if span.IsRecording() {
+		if size > 0 {
+			span.SetAttributes(attribute.Int("xdas.size.in", size))
+		}
+		if out != nil {
+			span.SetAttributes(attribute.Int("xdas.size.out", len(out)))
+		}
+	}
+	return out, endSpan(span, err)
+}
+
+// endSpan records err, if any, on span and returns it
+func endSpan(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
 }
 
 // Decrypt will return the decrypted data based on the encryption format
@@ -189,13 +346,20 @@ func Encrypt(encryption int, inData []byte) ([]byte, error) {
 	}
 }
 
-// Decompress will return the decompressed data based on the contentEncodingValue
+// Decompress will return the decompressed data based on the contentEncodingValue, limited to the
+// max set by SetMaxDecompressedSize
 func Decompress(contentEncodingValue int, inData []byte) ([]byte, error) {
+	return DecompressLimit(contentEncodingValue, inData, limits.Load().max)
+}
+
+// DecompressLimit will return the decompressed data based on the contentEncodingValue, or a
+// *SizeError if it is larger than limit. Data that can't be decompressed returns ErrCorrupt.
+func DecompressLimit(contentEncodingValue int, inData []byte, limit int64) ([]byte, error) {
 	switch contentEncodingValue {
 	case magicbyte.ContentEncodingNone:
 		return inData, nil
 	case magicbyte.ContentEncodingZstd:
-		return zstdDec.DecodeAll(inData, nil)
+		return decompressZstd(inData, limit)
 	case magicbyte.ContentEncodingZlib:
 		// to be implemented in the future
 		return inData, ErrUnknownEncodingType
@@ -204,6 +368,30 @@ func Decompress(contentEncodingValue int, inData []byte) ([]byte, error) {
 	}
 }
 
+func decompressZstd(inData []byte, limit int64) ([]byte, error) {
+	var buf bytes.Buffer
+	var header zstd.Header
+	if header.Decode(inData) == nil && header.HasFCS {
+		if header.FrameContentSize > uint64(limit) {
+			return nil, &SizeError{Limit: limit}
+		}
+		buf.Grow(int(header.FrameContentSize))
+	}
+	dec := zstdStreamDec.Get().(*zstd.Decoder)
+	defer zstdStreamDec.Put(dec)
+	if err := dec.Reset(bytes.NewReader(inData)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	n, err := buf.ReadFrom(io.LimitReader(dec, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	if n > limit {
+		return nil, &SizeError{Limit: limit}
+	}
+	return buf.Bytes(), nil
+}
+
 // Compress will return the compressed data based on the contentEncodingValue
 func Compress(contentEncodingValue int, inData []byte) ([]byte, error) {
 	switch contentEncodingValue {
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package conversion
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"xdas/internal/magicbyte"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestDecompressLimit(t *testing.T) {
+	data := bytes.Repeat([]byte("a"), 1000)
+	enc, _ := zstd.NewWriter(nil)
+	withSize := enc.EncodeAll(data, nil)
+	// streamed frames don't declare their content size
+	var stream bytes.Buffer
+	enc.Reset(&stream)
+	enc.Write(data)
+	enc.Close()
+
+	for _, in := range [][]byte{withSize, stream.Bytes()} {
+		out, err := DecompressLimit(magicbyte.ContentEncodingZstd, in, 1000)
+		if err != nil || !bytes.Equal(out, data) {
+			t.Errorf("DecompressLimit() got: %v bytes, %v, want: 1000 bytes", len(out), err)
+		}
+		_, err = DecompressLimit(magicbyte.ContentEncodingZstd, in, 999)
+		var sizeError *SizeError
+		if !errors.As(err, &sizeError) || sizeError.Limit != 999 {
+			t.Errorf("DecompressLimit() got: %v, want SizeError", err)
+		}
+	}
+
+	_, err := DecompressLimit(magicbyte.ContentEncodingZstd, []byte("garbage"), 1000)
+	if !errors.Is(err, ErrCorrupt) {
+		t.Errorf("DecompressLimit() got: %v, want ErrCorrupt", err)
+	}
+}
+
+func TestMaxDecompressedSize(t *testing.T) {
+	defer SetMaxDecompressedSize(0, nil)
+	SetMaxDecompressedSize(100, map[string]int64{"big": 1000})
+	if got := MaxDecompressedSize("big"); got != 1000 {
+		t.Errorf("MaxDecompressedSize(big) got: %v, want: 1000", got)
+	}
+	if got := MaxDecompressedSize("other"); got != 100 {
+		t.Errorf("MaxDecompressedSize(other) got: %v, want: 100", got)
+	}
+
+	in, _ := Compress(magicbyte.ContentEncodingZstd, bytes.Repeat([]byte("a"), 500))
+	_, _, err := Convert("other", magicbyte.NewMagicByte(magicbyte.ContentEncodingZstd, 0, 0),
+		magicbyte.NewMagicByte(magicbyte.ContentEncodingNone, 0, 0), in)
+	var sizeError *SizeError
+	if !errors.As(err, &sizeError) {
+		t.Errorf("Convert() got: %v, want SizeError", err)
+	}
+	_, out, err := Convert("big", magicbyte.NewMagicByte(magicbyte.ContentEncodingZstd, 0, 0),
+		magicbyte.NewMagicByte(magicbyte.ContentEncodingNone, 0, 0), in)
+	if err != nil || len(out) != 500 {
+		t.Errorf("Convert() got: %v bytes, %v, want: 500 bytes", len(out), err)
+	}
+
+	SetMaxDecompressedSize(0, nil)
+	if got := MaxDecompressedSize("big"); got != DefaultMaxDecompressedSize {
+		t.Errorf("MaxDecompressedSize() got: %v, want: %v", got, DefaultMaxDecompressedSize)
+	}
+}
+
+func TestStepDuration(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	Init(reg, "test", []string{"ks"})
+	defer func() { defaultMetrics = &noMetrics{} }()
+
+	in := magicbyte.NewMagicByte(magicbyte.ContentEncodingNone, magicbyte.ContentTypeProtoBuf, 0)
+	out := magicbyte.NewMagicByte(magicbyte.ContentEncodingZstd, magicbyte.ContentTypeProtoBuf, 0)
+	for _, keyspace := range []string{"ks", "other"} {
+		mb, data, err := Convert(keyspace, in, out, []byte("data"))
+		if err != nil || mb != out {
+			t.Fatalf("Convert() got: %v %v, want: %v", mb, err, out)
+		}
+		if _, _, err := Convert(keyspace, out, in, data); err != nil {
+			t.Fatal("Convert() returned error:", err)
+		}
+	}
+	// compress and decompress of each keyspace, unknown for keyspaces without metrics
+	if n := testutil.CollectAndCount(reg, "test_convert_step_duration_seconds"); n != 4 {
+		t.Errorf("step duration series got: %d, want: 4", n)
+	}
+}
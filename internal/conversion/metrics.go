@@ -18,7 +18,9 @@ package conversion
 
 import (
 	"errors"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -27,20 +29,24 @@ import (
 
 type metricsProvider interface {
 	init() error
+	addKeyspaces(keyspaces []string) error
 	incContentEncodingSuc(keyspace string)
 	incContentEncodingFail(keyspace string)
 	incContentTypeSuc(keyspace string)
 	incContentTypeFail(keyspace string)
 	incEncryptionSuc(keyspace string)
 	incEncryptionFail(keyspace string)
+	observeStep(keyspace, step string, d time.Duration)
 }
 
 type prometheusMetrics struct {
 	PromReg       prometheus.Registerer
 	PromNamespace string
 	Keyspaces     []string
-	counters      map[string]*counterType
+	counters      atomic.Pointer[map[string]*counterType] // copy on write, see addKeyspaces
 	unknown       *counterType
+	steps         *prometheus.HistogramVec
+	mu            sync.Mutex
 }
 
 type counterType struct {
@@ -56,10 +62,41 @@ func (p *prometheusMetrics) init() error {
 	if p.PromReg == nil || p.PromNamespace == "" {
 		return errors.New("Missing reg or namespace")
 	}
-	p.counters = make(map[string]*counterType)
-	for _, keyspace := range p.Keyspaces {
+	p.unknown = &counterType{}
+	p.counters.Store(&map[string]*counterType{})
+	p.steps = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: p.PromNamespace,
+			Subsystem: "convert",
+			Name:      "step_duration_seconds",
+			Help:      "A histogram of durations of conversion steps: decrypt, decompress, unmarshal, marshal, compress and encrypt.",
+			Buckets:   []float64{.00001, .0001, .0005, .001, .005, .01, .05, .1},
+		},
+		[]string{"keyspace", "step"},
+	)
+	if err := p.PromReg.Register(p.steps); err != nil {
+		return err
+	}
+	return p.addKeyspaces(p.Keyspaces)
+}
+
+// addKeyspaces registers counters for keyspaces that don't have one yet
+func (p *prometheusMetrics) addKeyspaces(keyspaces []string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	old := *p.counters.Load()
+	m := make(map[string]*counterType, len(old)+len(keyspaces))
+	for k, v := range old {
+		m[k] = v
+	}
+	defer p.counters.Store(&m)
+
+	for _, keyspace := range keyspaces {
+		if _, ok := m[keyspace]; ok {
+			continue
+		}
 		counterType := &counterType{}
-		p.counters[keyspace] = counterType
+		m[keyspace] = counterType
 
 		counters := []struct {
 			name    string
@@ -96,7 +133,7 @@ func (p *prometheusMetrics) init() error {
 }
 
 func (p *prometheusMetrics) incContentEncodingSuc(keyspace string) {
-	counter := p.counters[keyspace]
+	counter := (*p.counters.Load())[keyspace]
 	if counter != nil {
 		atomic.AddUint64(&counter.contentEncodingSuc, 1)
 	} else {
@@ -105,7 +142,7 @@ func (p *prometheusMetrics) incContentEncodingSuc(keyspace string) {
 }
 
 func (p *prometheusMetrics) incContentEncodingFail(keyspace string) {
-	counter := p.counters[keyspace]
+	counter := (*p.counters.Load())[keyspace]
 	if counter != nil {
 		atomic.AddUint64(&counter.contentEncodingFail, 1)
 	} else {
@@ -114,7 +151,7 @@ func (p *prometheusMetrics) incContentEncodingFail(keyspace string) {
 }
 
 func (p *prometheusMetrics) incContentTypeSuc(keyspace string) {
-	counter := p.counters[keyspace]
+	counter := (*p.counters.Load())[keyspace]
 	if counter != nil {
 		atomic.AddUint64(&counter.contentTypeSuc, 1)
 	} else {
@@ -123,7 +160,7 @@ func (p *prometheusMetrics) incContentTypeSuc(keyspace string) {
 }
 
 func (p *prometheusMetrics) incContentTypeFail(keyspace string) {
-	counter := p.counters[keyspace]
+	counter := (*p.counters.Load())[keyspace]
 	if counter != nil {
 		atomic.AddUint64(&counter.contentTypeFail, 1)
 	} else {
@@ -132,7 +169,7 @@ func (p *prometheusMetrics) incContentTypeFail(keyspace string) {
 }
 
 func (p *prometheusMetrics) incEncryptionSuc(keyspace string) {
-	counter := p.counters[keyspace]
+	counter := (*p.counters.Load())[keyspace]
 	if counter != nil {
 		atomic.AddUint64(&counter.encryptionSuc, 1)
 	} else {
@@ -141,7 +178,7 @@ func (p *prometheusMetrics) incEncryptionSuc(keyspace string) {
 }
 
 func (p *prometheusMetrics) incEncryptionFail(keyspace string) {
-	counter := p.counters[keyspace]
+	counter := (*p.counters.Load())[keyspace]
 	if counter != nil {
 		atomic.AddUint64(&counter.encryptionFail, 1)
 	} else {
@@ -149,12 +186,22 @@ func (p *prometheusMetrics) incEncryptionFail(keyspace string) {
 	}
 }
 
+// observeStep observes the duration of a conversion step, keyspaces without metrics are unknown
+func (p *prometheusMetrics) observeStep(keyspace, step string, d time.Duration) {
+	if _, ok := (*p.counters.Load())[keyspace]; !ok {
+		keyspace = "unknown"
+	}
+	p.steps.WithLabelValues(keyspace, step).Observe(d.Seconds())
+}
+
 type noMetrics struct{}
 
-func (n *noMetrics) init() error                            { return nil }
-func (n *noMetrics) incContentEncodingSuc(keyspace string)  {}
-func (n *noMetrics) incContentEncodingFail(keyspace string) {}
-func (n *noMetrics) incContentTypeSuc(keyspace string)      {}
-func (n *noMetrics) incContentTypeFail(keyspace string)     {}
-func (n *noMetrics) incEncryptionSuc(keyspace string)       {}
-func (n *noMetrics) incEncryptionFail(keyspace string)      {}
+func (n *noMetrics) init() error                                        { return nil }
+func (n *noMetrics) addKeyspaces(keyspaces []string) error              { return nil }
+func (n *noMetrics) incContentEncodingSuc(keyspace string)              {}
+func (n *noMetrics) incContentEncodingFail(keyspace string)             {}
+func (n *noMetrics) incContentTypeSuc(keyspace string)                  {}
+func (n *noMetrics) incContentTypeFail(keyspace string)                 {}
+func (n *noMetrics) incEncryptionSuc(keyspace string)                   {}
+func (n *noMetrics) incEncryptionFail(keyspace string)                  {}
+func (n *noMetrics) observeStep(keyspace, step string, d time.Duration) {}
@@ -17,6 +17,7 @@
 package findx
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -24,11 +25,27 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"xdas/internal/requestid"
+	"xdas/internal/tracing"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = tracing.Tracer("xdas/internal/findx")
+
 const (
 	DefaultChannelBufferSize = 128
 	DefaultThread            = 1
+
+	QueueChannel = "channel" // in-memory buffered channel, default
+	QueueStream  = "stream"  // Redis Streams backed, shared by all instances
 )
 
 // FindX defines parameters to run FindX service.
@@ -41,9 +58,24 @@ type FindX struct {
 	HTTPClient        *http.Client
 	UserAgent         string
 	Metrics           Metrics
+	Queue             string // QueueChannel (default) or QueueStream
+	Stream            Stream
+	Redis             redis.UniversalClient // required for QueueStream
 	enabled           bool
-	ch                chan string
+	mu                sync.RWMutex // guards enabled and ch against Add during Close
+	ch                chan entry
+	done              chan struct{}
+	cancel            context.CancelFunc // cancels the Redis calls of the stream workers on Close
 	wg                sync.WaitGroup
+	workers           atomic.Int32 // running workers
+	wantWorkers       int32
+}
+
+// entry is an id to look up, with the ID and the trace context of the request that added it
+type entry struct {
+	id        string
+	requestID string
+	trace     propagation.MapCarrier
 }
 
 // Start runs the FindX.
@@ -64,82 +96,169 @@ func (f *FindX) Start() error {
 	if f.Thread < 1 {
 		f.Thread = DefaultThread
 	}
-	f.ch = make(chan string, f.ChannelBufferSize)
-	f.wg.Add(f.Thread)
-	run := f.run
+	hclient := f.HTTPClient
+	if hclient == nil {
+		hclient = http.DefaultClient
+	}
+	send := f.send
 	if f.Keyspace == "dm" {
-		run = f.runDM
+		send = f.sendDM
 	}
-	for i := 0; i < f.Thread; i++ {
-		if f.HTTPClient != nil {
-			go run(f.HTTPClient, f.URL)
-		} else {
-			go run(http.DefaultClient, f.URL)
+
+	f.wantWorkers = 0
+	switch f.Queue {
+	case "", QueueChannel:
+		f.Queue = QueueChannel
+		f.ch = make(chan entry, f.ChannelBufferSize)
+		f.addWorkers(f.Thread)
+		for i := 0; i < f.Thread; i++ {
+			go f.run(hclient, send)
+		}
+	case QueueStream:
+		if err := f.startStream(hclient, send); err != nil {
+			f.Enabled = false
+			return err
 		}
+	default:
+		f.Enabled = false
+		return errors.New("unknown FindX queue " + f.Queue)
 	}
+	f.mu.Lock()
 	f.enabled = true
+	f.mu.Unlock()
 	return nil
 }
 
-// run is default processor for all keyspaces
-func (f *FindX) run(hclient *http.Client, URL string) {
-	defer f.wg.Done()
-	for id := range f.ch {
-		url := URL + id
-		f.getReq(hclient, url)
+// addWorkers accounts for n workers about to start, each calls workerDone when it exits
+func (f *FindX) addWorkers(n int) {
+	f.wantWorkers += int32(n)
+	f.workers.Add(int32(n))
+	f.wg.Add(n)
+}
+
+func (f *FindX) workerDone() {
+	f.workers.Add(-1)
+	f.wg.Done()
+}
+
+// Workers returns the number of running workers and the number started, both 0 if FindX is not
+// running. Fewer running than started means some have exited unexpectedly.
+func (f *FindX) Workers() (running, started int) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if !f.enabled {
+		return 0, 0
 	}
+	return int(f.workers.Load()), int(f.wantWorkers)
 }
 
-// RunDM for dm keyspace
-func (f *FindX) runDM(hclient *http.Client, URL string) {
-	defer f.wg.Done()
-	for id := range f.ch {
-		ids := strings.Split(id, ",")
-		if len(ids) < 2 {
-			f.Metrics.SentFail()
-			continue
-		}
-		url := URL + ids[0] + "?devices=" + ids[1]
-		f.getReq(hclient, url)
+// run is the processor for the channel queue
+func (f *FindX) run(hclient *http.Client, send func(*http.Client, entry) bool) {
+	defer f.workerDone()
+	for e := range f.ch {
+		send(hclient, e)
 	}
 }
 
-func (f *FindX) getReq(hclient *http.Client, url string) {
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+// send is default sender for all keyspaces. It returns false if the request should be retried.
+func (f *FindX) send(hclient *http.Client, e entry) bool {
+	return f.getReq(hclient, f.URL+e.id, e)
+}
+
+// sendDM for dm keyspace
+func (f *FindX) sendDM(hclient *http.Client, e entry) bool {
+	ids := strings.Split(e.id, ",")
+	if len(ids) < 2 {
+		f.Metrics.SentFail()
+		return true // malformed, retrying will not help
+	}
+	return f.getReq(hclient, f.URL+ids[0]+"?devices="+ids[1], e)
+}
+
+// getReq sends the request to FindX with the ID and the trace context of the request that
+// triggered it, if any. It returns false if it failed and may be retried.
+func (f *FindX) getReq(hclient *http.Client, url string, e entry) bool {
+	ctx := context.Background()
+	if e.trace != nil {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, e.trace)
+	}
+	ctx, span := tracer.Start(ctx, "findx "+f.Keyspace, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.HTTPRequestMethodKey.String(http.MethodGet),
+			attribute.String("xdas.keyspace", f.Keyspace)))
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		fmt.Println("create request err", err)
+		span.SetStatus(codes.Error, err.Error())
+		f.Metrics.SentFail()
+		return true
 	}
 	req.Header.Set("User-Agent", f.UserAgent)
+	if e.requestID != "" {
+		req.Header.Set(requestid.Header, e.requestID)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
 	resp, err := hclient.Do(req)
 	if err != nil {
 		fmt.Println("findx err", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		f.Metrics.SentFail()
-		return
+		return false
 	}
 	defer resp.Body.Close()
+	defer io.Copy(io.Discard, resp.Body) // Ensure keepalive
 
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= 500 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
 	switch {
 	case resp.StatusCode < 300:
 		f.Metrics.SentSuc()
+		return true
 	case resp.StatusCode < 500:
 		fmt.Println("findx non-2xx code:", resp.StatusCode, url)
 		f.Metrics.SentRej()
+		return true
 	default:
 		fmt.Println("findx non-2xx code:", resp.StatusCode, url)
 		f.Metrics.SentFail()
+		return false
 	}
-
-	io.Copy(io.Discard, resp.Body) // Ensure keepalive
 }
 
-// Add an entry to look up through FindX, it is non-blocking.
+// Add an entry to look up through FindX. It is non-blocking for the channel queue,
+// for the stream queue it makes a single XADD call to Redis, bounded by Stream.AddTimeout.
 func (f *FindX) Add(id string) {
+	f.AddContext(context.Background(), id)
+}
+
+// AddContext is like Add, the request ID and the trace context in ctx are sent to FindX with
+// the lookup, so it is traced as part of the request
+func (f *FindX) AddContext(ctx context.Context, id string) {
+	e := entry{id: id, requestID: requestid.FromContext(ctx)}
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		e.trace = propagation.MapCarrier{}
+		otel.GetTextMapPropagator().Inject(ctx, e.trace)
+	}
+	f.mu.RLock()
 	if !f.enabled {
+		f.mu.RUnlock()
 		return
 	}
+	if f.Queue == QueueStream {
+		// the XADD is made without the lock, so Close doesn't wait for it
+		rdb, stream := f.Redis, f.Stream
+		f.mu.RUnlock()
+		f.addStream(ctx, rdb, stream, e)
+		return
+	}
+	defer f.mu.RUnlock()
 	select {
-	case f.ch <- id:
+	case f.ch <- e:
 		f.Metrics.AddSuc()
 	default:
 		f.Metrics.AddFail()
@@ -148,6 +267,8 @@ func (f *FindX) Add(id string) {
 
 // Reject updates reject FindX metrics
 func (f *FindX) Reject() {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
 	if !f.enabled {
 		return
 	}
@@ -156,11 +277,26 @@ func (f *FindX) Reject() {
 
 // Close the FindX service
 func (f *FindX) Close() {
+	f.mu.Lock()
 	if !f.enabled {
+		f.mu.Unlock()
 		return
 	}
-	f.Enabled = false // Not atomic, but okay, closing down anyway
+	f.Enabled = false
 	f.enabled = false
-	close(f.ch)
+	if f.Queue == QueueStream {
+		close(f.done)
+		f.cancel()
+	} else {
+		close(f.ch)
+	}
+	f.mu.Unlock()
 	f.wg.Wait()
+	f.Metrics.unregisterPrometheus()
+}
+
+// UnregisterMetrics removes the metrics of f, so that a FindX of the same keyspace can be started
+// before f is closed
+func (f *FindX) UnregisterMetrics() {
+	f.Metrics.unregisterPrometheus()
 }
@@ -18,10 +18,20 @@ package findx
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"net/http"
 	"testing"
 	"time"
+	"xdas/internal/requestid"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // RoundTripFunc .
@@ -216,3 +226,143 @@ func TestAdd(t *testing.T) {
 		}
 	})
 }
+
+func TestAddContext(t *testing.T) {
+	got := make(chan string, 2)
+	hClient := NewTestClient(func(req *http.Request) *http.Response {
+		got <- req.Header.Get(requestid.Header)
+		return &http.Response{
+			StatusCode: 202,
+			Body:       io.NopCloser(bytes.NewBufferString(`OK`)),
+			Header:     make(http.Header),
+		}
+	})
+	queues := map[string]func(*FindX){
+		QueueChannel: func(f *FindX) {},
+		QueueStream: func(f *FindX) {
+			f.Redis = redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+			f.Stream = Stream{Block: 10 * time.Millisecond}
+		},
+	}
+	for queue, setup := range queues {
+		findX := &FindX{
+			Enabled:    true,
+			Keyspace:   "test",
+			URL:        "http://test/findx/",
+			HTTPClient: hClient,
+			Queue:      queue,
+		}
+		setup(findX)
+		if err := findX.Start(); err != nil {
+			t.Fatal("Start() returned error:", err)
+		}
+		findX.AddContext(requestid.NewContext(context.Background(), "req-1"), "a")
+		findX.Add("b")
+		for _, want := range []string{"req-1", ""} {
+			select {
+			case id := <-got:
+				if id != want {
+					t.Errorf("%s: request ID got: %q, want: %q", queue, id, want)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("%s: no request sent", queue)
+			}
+		}
+		findX.Close()
+	}
+}
+
+func TestAddContextTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	got := make(chan trace.SpanContext, 1)
+	hClient := NewTestClient(func(req *http.Request) *http.Response {
+		ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(req.Header))
+		got <- trace.SpanContextFromContext(ctx)
+		return &http.Response{
+			StatusCode: 202,
+			Body:       io.NopCloser(bytes.NewBufferString(`OK`)),
+			Header:     make(http.Header),
+		}
+	})
+	queues := map[string]func(*FindX){
+		QueueChannel: func(f *FindX) {},
+		QueueStream: func(f *FindX) {
+			f.Redis = redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+			f.Stream = Stream{Block: 10 * time.Millisecond}
+		},
+	}
+	for queue, setup := range queues {
+		findX := &FindX{
+			Enabled:    true,
+			Keyspace:   "test",
+			URL:        "http://test/findx/",
+			HTTPClient: hClient,
+			Queue:      queue,
+		}
+		setup(findX)
+		if err := findX.Start(); err != nil {
+			t.Fatal("Start() returned error:", err)
+		}
+		ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+		findX.AddContext(ctx, "a")
+		parent.End()
+		select {
+		case sc := <-got:
+			if sc.TraceID() != parent.SpanContext().TraceID() {
+				t.Errorf("%s: trace ID got: %s, want: %s", queue, sc.TraceID(), parent.SpanContext().TraceID())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: no request sent", queue)
+		}
+		findX.Close()
+
+		var found bool
+		for _, span := range recorder.Ended() {
+			if span.Name() == "findx test" && span.Parent().SpanID() == parent.SpanContext().SpanID() {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s: no findx span with the request span as parent", queue)
+		}
+	}
+}
+
+func TestWorkers(t *testing.T) {
+	queues := map[string]func(*FindX){
+		QueueChannel: func(f *FindX) {},
+		QueueStream: func(f *FindX) {
+			f.Redis = redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+			f.Stream = Stream{Block: 10 * time.Millisecond}
+		},
+	}
+	want := map[string]int{QueueChannel: 2, QueueStream: 3} // the stream queue has a claim worker
+	for queue, setup := range queues {
+		findX := &FindX{
+			Enabled:    true,
+			Keyspace:   "test",
+			URL:        "http://test/findx/",
+			HTTPClient: NewTestClient(nil),
+			Queue:      queue,
+			Thread:     2,
+		}
+		setup(findX)
+		if running, started := findX.Workers(); running != 0 || started != 0 {
+			t.Errorf("%s: before Start got: %d/%d, want: 0/0", queue, running, started)
+		}
+		if err := findX.Start(); err != nil {
+			t.Fatal("Start() returned error:", err)
+		}
+		if running, started := findX.Workers(); running != want[queue] || started != want[queue] {
+			t.Errorf("%s: got: %d/%d, want: %d/%d", queue, running, started, want[queue], want[queue])
+		}
+		findX.Close()
+		if running, started := findX.Workers(); running != 0 || started != 0 {
+			t.Errorf("%s: after Close got: %d/%d, want: 0/0", queue, running, started)
+		}
+	}
+}
@@ -28,12 +28,15 @@ type Metrics struct {
 	Keyspace      string
 	PromNamespace string
 	PromReg       prometheus.Registerer
+	collectors    []prometheus.Collector
 	addSuc        atomic.Uint64 // added to chan
 	addFail       atomic.Uint64 // chan full
 	addRej        atomic.Uint64 // reject adding to chan
 	sentSuc       atomic.Uint64 // sent to findX successfully
 	sentFail      atomic.Uint64 // sent to findX failed
 	sentRej       atomic.Uint64 // received 4xx from findX
+	sentDrop      atomic.Uint64 // gave up after max deliveries, stream queue only
+	claim         atomic.Uint64 // reclaimed pending entries, stream queue only
 }
 
 func (m *Metrics) initPrometheus() error {
@@ -58,28 +61,37 @@ func (m *Metrics) initPrometheus() error {
 		{"sent", "suc", &m.sentSuc},
 		{"sent", "fail", &m.sentFail},
 		{"sent", "rej", &m.sentRej},
+		{"sent", "drop", &m.sentDrop},
+		{"claim", "suc", &m.claim},
 	}
 	for _, c := range counters {
 		c := c
-		err := m.PromReg.Register(
-			prometheus.NewCounterFunc(
-				prometheus.CounterOpts{
-					Namespace:   m.PromNamespace,
-					Subsystem:   "findx",
-					Name:        c.name,
-					Help:        "A counter for total number of requests " + c.name + " to FindX",
-					ConstLabels: prometheus.Labels{"keyspace": m.Keyspace, "code": c.code},
-				},
-				func() float64 { return float64(c.counter.Load()) }),
-		)
-		if err != nil {
+		collector := prometheus.NewCounterFunc(
+			prometheus.CounterOpts{
+				Namespace:   m.PromNamespace,
+				Subsystem:   "findx",
+				Name:        c.name,
+				Help:        "A counter for total number of requests " + c.name + " to FindX",
+				ConstLabels: prometheus.Labels{"keyspace": m.Keyspace, "code": c.code},
+			},
+			func() float64 { return float64(c.counter.Load()) })
+		if err := m.PromReg.Register(collector); err != nil {
 			return err
 		}
+		m.collectors = append(m.collectors, collector)
 	}
 
 	return nil
 }
 
+// unregisterPrometheus removes the registered counters, so they can be registered again
+func (m *Metrics) unregisterPrometheus() {
+	for _, c := range m.collectors {
+		m.PromReg.Unregister(c)
+	}
+	m.collectors = nil
+}
+
 // AddSuc increments addSuc
 func (m *Metrics) AddSuc() { m.addSuc.Add(1) }
 
@@ -97,3 +109,9 @@ func (m *Metrics) SentFail() { m.sentFail.Add(1) }
 
 // SentRej increments sentRej, when receive 4xx (mostly should be 429)
 func (m *Metrics) SentRej() { m.sentRej.Add(1) }
+
+// SentDrop increments sentDrop
+func (m *Metrics) SentDrop() { m.sentDrop.Add(1) }
+
+// Claim adds n to claim
+func (m *Metrics) Claim(n int) { m.claim.Add(uint64(n)) }
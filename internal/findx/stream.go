@@ -0,0 +1,263 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package findx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	DefaultStreamGroup         = "findx"
+	DefaultStreamMaxLen        = 100000
+	DefaultStreamBlock         = 2 * time.Second
+	DefaultStreamClaimIdle     = time.Minute
+	DefaultStreamMaxDeliveries = 5
+	DefaultStreamAddTimeout    = 100 * time.Millisecond
+)
+
+// Stream holds the settings for the Redis Streams backed queue. Entries are
+// XADDed by Add and consumed by a consumer group, they are only XACKed after
+// FindX has responded, so pending entries survive restarts and are reclaimed
+// by any instance once idle for ClaimIdle.
+type Stream struct {
+	Key           string        // default findx:{<keyspace>}
+	Group         string        // default findx
+	Consumer      string        // consumer name prefix, default hostname
+	MaxLen        int64         // approximate max length of the stream, default 100000
+	Block         time.Duration // XREADGROUP block time, unit in ns, default 2s
+	ClaimIdle     time.Duration // reclaim pending entries idle longer than this, unit in ns, default 1m
+	MaxDeliveries int64         // drop entries delivered more than this, default 5
+	AddTimeout    time.Duration // XADD timeout of Add, unit in ns, default 100ms
+}
+
+func (f *FindX) startStream(hclient *http.Client, send func(*http.Client, entry) bool) error {
+	if f.Redis == nil {
+		return errors.New("FindX stream queue requires Redis")
+	}
+	s := &f.Stream
+	if s.Key == "" {
+		s.Key = "findx:{" + f.Keyspace + "}"
+	}
+	if s.Group == "" {
+		s.Group = DefaultStreamGroup
+	}
+	if s.Consumer == "" {
+		s.Consumer, _ = os.Hostname()
+	}
+	if s.MaxLen < 1 {
+		s.MaxLen = DefaultStreamMaxLen
+	}
+	if s.Block <= 0 {
+		s.Block = DefaultStreamBlock
+	}
+	if s.ClaimIdle <= 0 {
+		s.ClaimIdle = DefaultStreamClaimIdle
+	}
+	if s.MaxDeliveries < 1 {
+		s.MaxDeliveries = DefaultStreamMaxDeliveries
+	}
+	if s.AddTimeout <= 0 {
+		s.AddTimeout = DefaultStreamAddTimeout
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := f.createGroup(ctx); err != nil {
+		cancel()
+		return err
+	}
+
+	f.done = make(chan struct{})
+	f.cancel = cancel
+	f.addWorkers(f.Thread + 1)
+	for i := 0; i < f.Thread; i++ {
+		go f.runStream(ctx, hclient, send, s.Consumer+"-"+strconv.Itoa(i))
+	}
+	go f.runClaim(ctx, hclient, send, s.Consumer+"-claim")
+	return nil
+}
+
+// createGroup creates the consumer group, and the stream if needed. The group reads the stream
+// from the start, so entries added while it was missing are not lost.
+func (f *FindX) createGroup(ctx context.Context) error {
+	err := f.Redis.XGroupCreateMkStream(ctx, f.Stream.Key, f.Stream.Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+// isNoGroup reports whether err is for a missing stream or group, e.g. after the key was deleted
+// or a failover lost it
+func isNoGroup(err error) bool {
+	return strings.HasPrefix(err.Error(), "NOGROUP")
+}
+
+// addStream XADDs e to the stream of s with rdb, they are copied from f by the caller
+func (f *FindX) addStream(ctx context.Context, rdb redis.UniversalClient, s Stream, e entry) {
+	ctx, cancel := context.WithTimeout(ctx, s.AddTimeout)
+	defer cancel()
+	values := map[string]interface{}{"id": e.id}
+	if e.requestID != "" {
+		values["requestId"] = e.requestID
+	}
+	for k, v := range e.trace {
+		values[k] = v // traceparent, tracestate
+	}
+	err := rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.Key,
+		MaxLen: s.MaxLen,
+		Approx: true,
+		Values: values,
+	}).Err()
+	if err != nil {
+		f.Metrics.AddFail()
+		return
+	}
+	f.Metrics.AddSuc()
+}
+
+// runStream is the processor for the stream queue
+func (f *FindX) runStream(ctx context.Context, hclient *http.Client, send func(*http.Client, entry) bool, consumer string) {
+	defer f.workerDone()
+	for {
+		select {
+		case <-f.done:
+			return
+		default:
+		}
+		streams, err := f.Redis.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    f.Stream.Group,
+			Consumer: consumer,
+			Streams:  []string{f.Stream.Key, ">"},
+			Count:    1,
+			Block:    f.Stream.Block,
+		}).Result()
+		if err != nil {
+			if err != redis.Nil && ctx.Err() == nil {
+				fmt.Println("findx stream err", err)
+				if isNoGroup(err) && f.createGroup(ctx) == nil {
+					continue
+				}
+				f.wait(f.Stream.Block)
+			}
+			continue
+		}
+		for _, stream := range streams {
+			f.process(ctx, hclient, send, stream.Messages)
+		}
+	}
+}
+
+// runClaim periodically takes over entries left pending by failed sends or by
+// consumers that are gone, and retries them.
+func (f *FindX) runClaim(ctx context.Context, hclient *http.Client, send func(*http.Client, entry) bool, consumer string) {
+	defer f.workerDone()
+	for f.wait(f.Stream.ClaimIdle) {
+		pending, err := f.Redis.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream: f.Stream.Key,
+			Group:  f.Stream.Group,
+			Start:  "-",
+			End:    "+",
+			Count:  int64(f.ChannelBufferSize),
+		}).Result()
+		if err != nil {
+			fmt.Println("findx stream pending err", err)
+			if isNoGroup(err) {
+				f.createGroup(ctx)
+			}
+			continue
+		}
+
+		ids := make([]string, 0, len(pending))
+		for _, p := range pending {
+			if p.Idle < f.Stream.ClaimIdle {
+				continue
+			}
+			if p.RetryCount > f.Stream.MaxDeliveries {
+				f.Redis.XAck(ctx, f.Stream.Key, f.Stream.Group, p.ID)
+				f.Metrics.SentDrop()
+				continue
+			}
+			ids = append(ids, p.ID)
+		}
+		if len(ids) == 0 {
+			continue
+		}
+
+		msgs, err := f.Redis.XClaim(ctx, &redis.XClaimArgs{
+			Stream:   f.Stream.Key,
+			Group:    f.Stream.Group,
+			Consumer: consumer,
+			MinIdle:  f.Stream.ClaimIdle,
+			Messages: ids,
+		}).Result()
+		if err != nil {
+			fmt.Println("findx stream claim err", err)
+			continue
+		}
+		f.Metrics.Claim(len(msgs))
+		f.process(ctx, hclient, send, msgs)
+	}
+}
+
+// process sends each message to FindX and acknowledges the ones that don't need a retry
+func (f *FindX) process(ctx context.Context, hclient *http.Client, send func(*http.Client, entry) bool, msgs []redis.XMessage) {
+	for _, msg := range msgs {
+		id, _ := msg.Values["id"].(string)
+		requestID, _ := msg.Values["requestId"].(string)
+		if id == "" || send(hclient, entry{id: id, requestID: requestID, trace: traceCarrier(msg)}) {
+			f.Redis.XAck(ctx, f.Stream.Key, f.Stream.Group, msg.ID)
+		}
+	}
+}
+
+// wait sleeps for d, returns false if FindX is closed in the meantime
+func (f *FindX) wait(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-f.done:
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// traceCarrier returns the trace context fields of msg, nil if it has none
+func traceCarrier(msg redis.XMessage) propagation.MapCarrier {
+	var c propagation.MapCarrier
+	for _, k := range otel.GetTextMapPropagator().Fields() {
+		if v, ok := msg.Values[k].(string); ok {
+			if c == nil {
+				c = propagation.MapCarrier{}
+			}
+			c[k] = v
+		}
+	}
+	return c
+}
@@ -0,0 +1,235 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package findx
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestStream(t *testing.T) {
+	url := "http://test/findx/"
+	hClient := NewTestClient(func(req *http.Request) *http.Response {
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(bytes.NewBufferString(`OK`)),
+			Header:     make(http.Header),
+		}
+	})
+	hClientFail := NewTestClient(func(req *http.Request) *http.Response {
+		return &http.Response{
+			StatusCode: 503,
+			Body:       io.NopCloser(bytes.NewBufferString(`OK`)),
+			Header:     make(http.Header),
+		}
+	})
+
+	t.Run("no redis", func(t *testing.T) {
+		findX := &FindX{Enabled: true, URL: url, Queue: QueueStream}
+		if err := findX.Start(); err == nil {
+			t.Error("Start() got nil error, want error")
+		}
+	})
+	t.Run("send success", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		findX := &FindX{
+			Enabled:    true,
+			Keyspace:   "test",
+			URL:        url,
+			HTTPClient: hClient,
+			Queue:      QueueStream,
+			Redis:      rdb,
+			Stream:     Stream{Block: 10 * time.Millisecond},
+		}
+		if err := findX.Start(); err != nil {
+			t.Fatal("Start() returned error:", err)
+		}
+		maxReq := 10
+		for i := 0; i < maxReq; i++ {
+			findX.Add("test")
+		}
+		waitFor(t, func() bool { return findX.Metrics.sentSuc.Load() == uint64(maxReq) })
+		findX.Close()
+		if actual := rdb.XPending(context.Background(), "findx:{test}", DefaultStreamGroup).Val().Count; actual != 0 {
+			t.Errorf("Incorrect number of pending got: %v, want: %v\n", actual, 0)
+		}
+	})
+	t.Run("send fail", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		findX := &FindX{
+			Enabled:    true,
+			Keyspace:   "test",
+			URL:        url,
+			HTTPClient: hClientFail,
+			Queue:      QueueStream,
+			Redis:      rdb,
+			Stream:     Stream{Block: 10 * time.Millisecond},
+		}
+		if err := findX.Start(); err != nil {
+			t.Fatal("Start() returned error:", err)
+		}
+		maxReq := 10
+		for i := 0; i < maxReq; i++ {
+			findX.Add("test")
+		}
+		waitFor(t, func() bool { return findX.Metrics.sentFail.Load() == uint64(maxReq) })
+		findX.Close()
+		if actual := rdb.XPending(context.Background(), "findx:{test}", DefaultStreamGroup).Val().Count; actual != int64(maxReq) {
+			t.Errorf("Incorrect number of pending got: %v, want: %v\n", actual, maxReq)
+		}
+	})
+}
+
+// slowXAdd is a redis.Hook that holds each XADD until release is closed, or until its context
+// is done if honorCtx is set
+type slowXAdd struct {
+	entered  chan struct{}
+	release  chan struct{}
+	honorCtx bool
+}
+
+func (h *slowXAdd) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *slowXAdd) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() == "xadd" {
+			h.entered <- struct{}{}
+			if h.honorCtx {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-h.release:
+				}
+			} else {
+				<-h.release
+			}
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h *slowXAdd) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestStreamSlowAdd(t *testing.T) {
+	newFindX := func(t *testing.T, h *slowXAdd) *FindX {
+		mr := miniredis.RunT(t)
+		rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		rdb.AddHook(h)
+		findX := &FindX{
+			Enabled:  true,
+			Keyspace: "test",
+			URL:      "http://test/findx/",
+			Queue:    QueueStream,
+			Redis:    rdb,
+			Stream:   Stream{Block: 10 * time.Millisecond, AddTimeout: 50 * time.Millisecond},
+		}
+		if err := findX.Start(); err != nil {
+			t.Fatal("Start() returned error:", err)
+		}
+		return findX
+	}
+
+	t.Run("close", func(t *testing.T) {
+		h := &slowXAdd{entered: make(chan struct{}), release: make(chan struct{})}
+		findX := newFindX(t, h)
+		added := make(chan struct{})
+		go func() {
+			findX.Add("test")
+			close(added)
+		}()
+		<-h.entered
+
+		closed := make(chan struct{})
+		go func() {
+			findX.Close()
+			close(closed)
+		}()
+		select {
+		case <-closed:
+		case <-time.After(time.Second):
+			t.Error("Close() blocked by XADD")
+		}
+		close(h.release)
+		<-added
+	})
+	t.Run("timeout", func(t *testing.T) {
+		h := &slowXAdd{entered: make(chan struct{}, 1), release: make(chan struct{}), honorCtx: true}
+		findX := newFindX(t, h)
+		defer findX.Close()
+		start := time.Now()
+		findX.AddContext(context.Background(), "test")
+		if d := time.Since(start); d > time.Second {
+			t.Errorf("AddContext() took %v, want about AddTimeout", d)
+		}
+		if actual := findX.Metrics.addFail.Load(); actual != 1 {
+			t.Errorf("Incorrect number of add fail got: %v, want: %v\n", actual, 1)
+		}
+	})
+}
+
+func TestStreamNoGroup(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	hClient := NewTestClient(func(req *http.Request) *http.Response {
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(bytes.NewBufferString(`OK`)), Header: make(http.Header)}
+	})
+	findX := &FindX{
+		Enabled:    true,
+		Keyspace:   "test",
+		URL:        "http://test/findx/",
+		HTTPClient: hClient,
+		Queue:      QueueStream,
+		Redis:      rdb,
+		Stream:     Stream{Block: 10 * time.Millisecond},
+	}
+	if err := findX.Start(); err != nil {
+		t.Fatal("Start() returned error:", err)
+	}
+	defer findX.Close()
+
+	// the stream and its group are lost, the workers recreate the group and consume new entries
+	mr.Del("findx:{test}")
+	findX.Add("test")
+	findX.Add("test")
+	waitFor(t, func() bool { return findX.Metrics.sentSuc.Load() == 2 })
+	groups, err := rdb.XInfoGroups(context.Background(), "findx:{test}").Result()
+	if err != nil || len(groups) != 1 || groups[0].Name != DefaultStreamGroup {
+		t.Errorf("XInfoGroups() got: %v, %v, want group %s", groups, err, DefaultStreamGroup)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for condition")
+}
@@ -85,3 +85,18 @@ func (l *Logger) WithGroup(name string) *Logger {
 	c.Logger = l.Logger.WithGroup(name)
 	return &c
 }
+
+type contextKey struct{}
+
+// NewContext returns a new Context that carries l, e.g. a Logger With attributes of a request
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the Logger in ctx, or l if there is none
+func FromContext(ctx context.Context, l *Logger) *Logger {
+	if c, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return c
+	}
+	return l
+}
@@ -18,6 +18,7 @@ package weblog
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -33,13 +34,30 @@ func WebLogChiMiddleware(l *logger.Logger) func(http.Handler) http.Handler {
 	}
 }
 
+type attrsKey struct{}
+
+// AddAttrs adds key-value pairs, as in slog, to the log of the request by a WebLog middleware.
+// It lets handlers after the middleware, e.g. authentication, add to the log.
+func AddAttrs(ctx context.Context, args ...any) {
+	if attrs, ok := ctx.Value(attrsKey{}).(*[]any); ok {
+		*attrs = append(*attrs, args...)
+	}
+}
+
+// withAttrs returns the request with a context for AddAttrs
+func withAttrs(r *http.Request) (*http.Request, *[]any) {
+	attrs := new([]any)
+	return r.WithContext(context.WithValue(r.Context(), attrsKey{}, attrs)), attrs
+}
+
 // WebLog is a middleware that logs the requests with the response code
 func WebLog(l *logger.Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		lrw := NewLogResponseWriter(w)
+		r, attrs := withAttrs(r)
 		next.ServeHTTP(lrw, r)
-		l.Debug("request", "method", r.Method, "path", r.URL.Path, "addr", r.RemoteAddr,
-			"ua", r.UserAgent(), "code", lrw.statusCode)
+		l.Debug("request", append([]any{"method", r.Method, "path", r.URL.Path, "addr", r.RemoteAddr,
+			"ua", r.UserAgent(), "code", lrw.statusCode}, *attrs...)...)
 	})
 }
 
@@ -57,10 +75,11 @@ func WebLogBody(l *logger.Logger, next http.Handler) http.Handler {
 		}
 
 		r.Body = save
+		r, attrs := withAttrs(r)
 		next.ServeHTTP(lrw, r)
 
-		l.Debug("request", "method", r.Method, "path", r.URL.Path, "addr", r.RemoteAddr, "ua", r.UserAgent(),
-			"code", lrw.statusCode, "body", RawJSON(body))
+		l.Debug("request", append([]any{"method", r.Method, "path", r.URL.Path, "addr", r.RemoteAddr,
+			"ua", r.UserAgent(), "code", lrw.statusCode, "body", RawJSON(body)}, *attrs...)...)
 	})
 }
 
@@ -78,10 +97,12 @@ func WebLogBodyResponse(l *logger.Logger, next http.Handler) http.Handler {
 		}
 
 		r.Body = save
+		r, attrs := withAttrs(r)
 		next.ServeHTTP(lrw, r)
 
-		l.Debug("request", "method", r.Method, "path", r.URL.Path, "addr", r.RemoteAddr, "ua", r.UserAgent(),
-			"code", lrw.statusCode, "body", RawJSON(body), "response", RawJSON(lrw.response.String()))
+		l.Debug("request", append([]any{"method", r.Method, "path", r.URL.Path, "addr", r.RemoteAddr,
+			"ua", r.UserAgent(), "code", lrw.statusCode, "body", RawJSON(body),
+			"response", RawJSON(lrw.response.String())}, *attrs...)...)
 	})
 }
 
@@ -140,6 +161,11 @@ func (lrw *logBodyResponseWriter) Write(data []byte) (int, error) {
 	return lrw.ResponseWriter.Write(data)
 }
 
+// Unwrap returns the original http.ResponseWriter for http.ResponseController
+func (lrw *logBodyResponseWriter) Unwrap() http.ResponseWriter {
+	return lrw.ResponseWriter
+}
+
 // logResponseWriter implments http.ResponseWriter, capture respoonse status code
 type logResponseWriter struct {
 	http.ResponseWriter
@@ -154,3 +180,8 @@ func (lrw *logResponseWriter) WriteHeader(code int) {
 	lrw.statusCode = code
 	lrw.ResponseWriter.WriteHeader(code)
 }
+
+// Unwrap returns the original http.ResponseWriter for http.ResponseController
+func (lrw *logResponseWriter) Unwrap() http.ResponseWriter {
+	return lrw.ResponseWriter
+}
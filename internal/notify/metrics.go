@@ -0,0 +1,111 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package notify
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics holds metrics info
+type Metrics struct {
+	Keyspace      string
+	PromNamespace string
+	PromReg       prometheus.Registerer
+	collectors    []prometheus.Collector
+	addSuc        atomic.Uint64 // added to chan
+	addFail       atomic.Uint64 // chan full
+	sentSuc       atomic.Uint64 // sent to webhook successfully
+	sentFail      atomic.Uint64 // sent to webhook failed after retries
+	sentRej       atomic.Uint64 // received 4xx from webhook
+	sentDrop      atomic.Uint64 // dropped on Close, pending or in progress
+	retry         atomic.Uint64 // retried sends
+}
+
+func (m *Metrics) initPrometheus() error {
+	if m.PromReg == nil {
+		m.PromReg = prometheus.DefaultRegisterer
+	}
+	if m.Keyspace == "" {
+		return errors.New("missing keyspace")
+	}
+	if m.PromNamespace == "" {
+		return errors.New("missing PromNamespace")
+	}
+	counters := []struct {
+		name    string
+		code    string
+		counter *atomic.Uint64
+	}{
+		{"add", "suc", &m.addSuc},
+		{"add", "fail", &m.addFail},
+		{"sent", "suc", &m.sentSuc},
+		{"sent", "fail", &m.sentFail},
+		{"sent", "rej", &m.sentRej},
+		{"sent", "drop", &m.sentDrop},
+		{"sent", "retry", &m.retry},
+	}
+	for _, c := range counters {
+		c := c
+		collector := prometheus.NewCounterFunc(
+			prometheus.CounterOpts{
+				Namespace:   m.PromNamespace,
+				Subsystem:   "notify",
+				Name:        c.name,
+				Help:        "A counter for total number of notifications " + c.name + " to webhook",
+				ConstLabels: prometheus.Labels{"keyspace": m.Keyspace, "code": c.code},
+			},
+			func() float64 { return float64(c.counter.Load()) })
+		if err := m.PromReg.Register(collector); err != nil {
+			return err
+		}
+		m.collectors = append(m.collectors, collector)
+	}
+
+	return nil
+}
+
+// unregisterPrometheus removes the registered counters, so they can be registered again
+func (m *Metrics) unregisterPrometheus() {
+	for _, c := range m.collectors {
+		m.PromReg.Unregister(c)
+	}
+	m.collectors = nil
+}
+
+// AddSuc increments addSuc
+func (m *Metrics) AddSuc() { m.addSuc.Add(1) }
+
+// AddFail increments addFail
+func (m *Metrics) AddFail() { m.addFail.Add(1) }
+
+// SentSuc increments sentSuc
+func (m *Metrics) SentSuc() { m.sentSuc.Add(1) }
+
+// SentFail increments sentFail
+func (m *Metrics) SentFail() { m.sentFail.Add(1) }
+
+// SentDrop increments sentDrop
+func (m *Metrics) SentDrop() { m.sentDrop.Add(1) }
+
+// SentRej increments sentRej
+func (m *Metrics) SentRej() { m.sentRej.Add(1) }
+
+// Retry increments retry
+func (m *Metrics) Retry() { m.retry.Add(1) }
@@ -0,0 +1,273 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultChannelBufferSize = 128
+	DefaultThread            = 1
+	DefaultMaxRetry          = 3
+	DefaultRetryWait         = 500 * time.Millisecond
+
+	OpPut = "put"
+	OpDel = "del"
+	OpInc = "inc"
+)
+
+// Event is a change of a record in a keyspace
+type Event struct {
+	Op              string
+	ID              string
+	TTL             time.Duration
+	ContentType     string
+	ContentEncoding string
+	Body            []byte
+}
+
+// Notify defines parameters to send change notifications of a keyspace to a webhook.
+// Each event is POSTed to URL with the headers Xdas-Keyspace, Xdas-Id, Xdas-Op and Xttl,
+// and the record as body if IncludeBody is set.
+type Notify struct {
+	Enabled           bool
+	Keyspace          string
+	URL               string
+	Methods           []string // ops to notify on: put, del, inc. Default all
+	IncludeBody       bool
+	ChannelBufferSize int
+	Thread            int
+	MaxRetry          int           // retries on error or 5xx, default 3, negative to disable
+	RetryWait         time.Duration // initial wait between retries, doubled each retry, unit in ns, default 500ms
+	HTTPClient        *http.Client
+	UserAgent         string
+	Metrics           Metrics
+	enabled           bool
+	mu                sync.RWMutex // guards enabled and ch against Add during Close
+	methods           map[string]bool
+	ch                chan Event
+	ctx               context.Context // canceled on Close, ends sends and retries
+	cancel            context.CancelFunc
+	wg                sync.WaitGroup
+}
+
+// Start runs the Notify.
+func (n *Notify) Start() error {
+	if !n.Enabled {
+		return errors.New("Notify not Enabled")
+	}
+	if _, err := url.ParseRequestURI(n.URL); err != nil {
+		n.Enabled = false
+		return err
+	}
+	if len(n.Methods) == 0 {
+		n.Methods = []string{OpPut, OpDel, OpInc}
+	}
+	n.methods = make(map[string]bool, len(n.Methods))
+	for _, m := range n.Methods {
+		switch m {
+		case OpPut, OpDel, OpInc:
+			n.methods[m] = true
+		default:
+			n.Enabled = false
+			return errors.New("unknown notify method " + m)
+		}
+	}
+	if err := n.Metrics.initPrometheus(); err != nil {
+		// should we panic or ignore
+	}
+	if n.ChannelBufferSize < 1 {
+		n.ChannelBufferSize = DefaultChannelBufferSize
+	}
+	if n.Thread < 1 {
+		n.Thread = DefaultThread
+	}
+	if n.MaxRetry < 0 {
+		n.MaxRetry = 0
+	} else if n.MaxRetry == 0 {
+		n.MaxRetry = DefaultMaxRetry
+	}
+	if n.RetryWait <= 0 {
+		n.RetryWait = DefaultRetryWait
+	}
+	hclient := n.HTTPClient
+	if hclient == nil {
+		hclient = http.DefaultClient
+	}
+	n.ch = make(chan Event, n.ChannelBufferSize)
+	n.ctx, n.cancel = context.WithCancel(context.Background())
+	n.wg.Add(n.Thread)
+	for i := 0; i < n.Thread; i++ {
+		go n.run(hclient)
+	}
+	n.mu.Lock()
+	n.enabled = true
+	n.mu.Unlock()
+	return nil
+}
+
+func (n *Notify) run(hclient *http.Client) {
+	defer n.wg.Done()
+	for e := range n.ch {
+		if n.ctx.Err() != nil { // closed, the remaining events are dropped
+			n.Metrics.SentDrop()
+			continue
+		}
+		n.send(hclient, e)
+	}
+}
+
+// send posts the event, retrying with exponential backoff on error or 5xx. The event is dropped
+// if Close is called meanwhile.
+func (n *Notify) send(hclient *http.Client, e Event) {
+	wait := n.RetryWait
+	for retries := 0; ; retries++ {
+		retry := n.postReq(hclient, e)
+		if !retry {
+			return
+		}
+		if n.ctx.Err() != nil {
+			n.Metrics.SentDrop()
+			return
+		}
+		if retries >= n.MaxRetry {
+			n.Metrics.SentFail()
+			return
+		}
+		n.Metrics.Retry()
+		timer := time.NewTimer(wait)
+		select {
+		case <-n.ctx.Done():
+			timer.Stop()
+			n.Metrics.SentDrop()
+			return
+		case <-timer.C:
+		}
+		wait *= 2
+	}
+}
+
+// postReq returns true if the request should be retried
+func (n *Notify) postReq(hclient *http.Client, e Event) bool {
+	req, err := http.NewRequestWithContext(n.ctx, http.MethodPost, n.URL, bytes.NewReader(e.Body))
+	if err != nil {
+		fmt.Println("create notify request err", err)
+		n.Metrics.SentFail()
+		return false
+	}
+	req.Header.Set("User-Agent", n.UserAgent)
+	req.Header.Set("Xdas-Keyspace", n.Keyspace)
+	req.Header.Set("Xdas-Id", e.ID)
+	req.Header.Set("Xdas-Op", e.Op)
+	if e.TTL > 0 {
+		req.Header.Set("Xttl", strconv.FormatInt(int64(e.TTL/time.Second), 10))
+	}
+	if len(e.Body) > 0 {
+		if e.ContentType != "" {
+			req.Header.Set("Content-type", e.ContentType)
+		}
+		if e.ContentEncoding != "" {
+			req.Header.Set("Content-encoding", e.ContentEncoding)
+		}
+	}
+
+	resp, err := hclient.Do(req)
+	if err != nil {
+		if n.ctx.Err() == nil {
+			fmt.Println("notify err", err)
+		}
+		return true
+	}
+	defer resp.Body.Close()
+	defer io.Copy(io.Discard, resp.Body) // Ensure keepalive
+
+	switch {
+	case resp.StatusCode < 300:
+		n.Metrics.SentSuc()
+		return false
+	case resp.StatusCode < 500:
+		fmt.Println("notify non-2xx code:", resp.StatusCode, n.URL)
+		n.Metrics.SentRej()
+		return false
+	default:
+		fmt.Println("notify non-2xx code:", resp.StatusCode, n.URL)
+		return true
+	}
+}
+
+// Wants returns true if op should be notified
+func (n *Notify) Wants(op string) bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.enabled && n.methods[op]
+}
+
+// WantsBody returns true if op should be notified with the record as body
+func (n *Notify) WantsBody(op string) bool {
+	return n.IncludeBody && n.Wants(op)
+}
+
+// Add an event to be notified, it is non-blocking. Events for ops not in Methods are ignored.
+func (n *Notify) Add(e Event) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	if !n.enabled || !n.methods[e.Op] {
+		return
+	}
+	if !n.IncludeBody {
+		e.Body = nil
+	}
+	select {
+	case n.ch <- e:
+		n.Metrics.AddSuc()
+	default:
+		n.Metrics.AddFail()
+	}
+}
+
+// Close the Notify service. Sends in progress are canceled, they and the pending events are
+// dropped and counted in the sent drop metric.
+func (n *Notify) Close() {
+	n.mu.Lock()
+	if !n.enabled {
+		n.mu.Unlock()
+		return
+	}
+	n.Enabled = false
+	n.enabled = false
+	n.cancel()
+	close(n.ch)
+	n.mu.Unlock()
+	n.wg.Wait()
+	n.Metrics.unregisterPrometheus()
+}
+
+// UnregisterMetrics removes the metrics of n, so that a Notify of the same keyspace can be started
+// before n is closed
+func (n *Notify) UnregisterMetrics() {
+	n.Metrics.unregisterPrometheus()
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package notify
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// RoundTripFunc .
+type RoundTripFunc func(req *http.Request) *http.Response
+
+// RoundTrip .
+func (f RoundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req), nil
+}
+
+// NewTestClient returns *http.Client with Transport replaced to avoid making real calls
+func NewTestClient(fn RoundTripFunc) *http.Client {
+	return &http.Client{
+		Transport: RoundTripFunc(fn),
+	}
+}
+
+func newResponse(code int) *http.Response {
+	return &http.Response{
+		StatusCode: code,
+		Body:       io.NopCloser(bytes.NewBufferString(`OK`)),
+		Header:     make(http.Header),
+	}
+}
+
+func TestStart(t *testing.T) {
+	tests := []struct {
+		notify  *Notify
+		wantErr bool
+	}{
+		{&Notify{}, true},
+		{&Notify{Enabled: true}, true},
+		{&Notify{Enabled: true, URL: "http://test/notify", Methods: []string{"get"}}, true},
+		{&Notify{Enabled: true, URL: "http://test/notify", Methods: []string{OpPut}}, false},
+		{&Notify{Enabled: true, URL: "http://test/notify"}, false},
+	}
+	for _, tt := range tests {
+		err := tt.notify.Start()
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Start() got nil error, want error\n")
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Start() returned error: %v\n", err)
+			continue
+		}
+		if !tt.notify.enabled {
+			t.Error("Notify.enable want: true, got:", tt.notify.enabled)
+		}
+		tt.notify.Close()
+	}
+}
+
+func TestSend(t *testing.T) {
+	url := "http://test/notify"
+	t.Run("send success", func(t *testing.T) {
+		hClient := NewTestClient(func(req *http.Request) *http.Response {
+			if req.Header.Get("Xdas-Keyspace") != "test" || req.Header.Get("Xdas-Id") != "ID1" ||
+				req.Header.Get("Xdas-Op") != OpPut || req.Header.Get("Xttl") != "60" {
+				t.Errorf("Wrong headers got: %v\n", req.Header)
+			}
+			body, _ := io.ReadAll(req.Body)
+			if string(body) != "{}" {
+				t.Errorf("Wrong body got: %s, want: {}\n", body)
+			}
+			return newResponse(200)
+		})
+		n := &Notify{Enabled: true, Keyspace: "test", URL: url, IncludeBody: true, HTTPClient: hClient}
+		n.Start()
+		maxReq := 10
+		for i := 0; i < maxReq; i++ {
+			n.Add(Event{Op: OpPut, ID: "ID1", TTL: time.Minute, Body: []byte("{}")})
+		}
+		waitFor(t, func() bool { return n.Metrics.sentSuc.Load() == uint64(maxReq) })
+		n.Close()
+	})
+	t.Run("method filtered", func(t *testing.T) {
+		hClient := NewTestClient(func(req *http.Request) *http.Response { return newResponse(200) })
+		n := &Notify{Enabled: true, URL: url, Methods: []string{OpDel}, HTTPClient: hClient}
+		n.Start()
+		n.Add(Event{Op: OpPut, ID: "ID1"})
+		n.Add(Event{Op: OpDel, ID: "ID1"})
+		n.Close()
+		if actual := n.Metrics.addSuc.Load(); actual != 1 {
+			t.Errorf("Incorrect number of addSuc got: %v, want: %v\n", actual, 1)
+		}
+	})
+	t.Run("retry then fail", func(t *testing.T) {
+		var calls atomic.Int32
+		hClient := NewTestClient(func(req *http.Request) *http.Response {
+			calls.Add(1)
+			return newResponse(503)
+		})
+		n := &Notify{Enabled: true, URL: url, MaxRetry: 2, RetryWait: time.Millisecond, HTTPClient: hClient}
+		n.Start()
+		n.Add(Event{Op: OpDel, ID: "ID1"})
+		waitFor(t, func() bool { return n.Metrics.sentFail.Load() == 1 })
+		n.Close()
+		if actual := calls.Load(); actual != 3 {
+			t.Errorf("Incorrect number of calls got: %v, want: %v\n", actual, 3)
+		}
+		if actual := n.Metrics.sentFail.Load(); actual != 1 {
+			t.Errorf("Incorrect number of sentFail got: %v, want: %v\n", actual, 1)
+		}
+	})
+	t.Run("rej not retried", func(t *testing.T) {
+		hClient := NewTestClient(func(req *http.Request) *http.Response { return newResponse(400) })
+		n := &Notify{Enabled: true, URL: url, RetryWait: time.Millisecond, HTTPClient: hClient}
+		n.Start()
+		n.Add(Event{Op: OpDel, ID: "ID1"})
+		waitFor(t, func() bool { return n.Metrics.sentRej.Load() == 1 })
+		n.Close()
+		if actual := n.Metrics.retry.Load(); actual != 0 {
+			t.Errorf("Incorrect number of retry got: %v, want: %v\n", actual, 0)
+		}
+	})
+}
+
+func TestCloseDrops(t *testing.T) {
+	sending := make(chan struct{}, 1)
+	hClient := NewTestClient(func(req *http.Request) *http.Response {
+		select {
+		case sending <- struct{}{}:
+		default:
+		}
+		return newResponse(503)
+	})
+	// the webhook is down and retries wait long, Close must not wait for them
+	n := &Notify{Enabled: true, URL: "http://test/notify", RetryWait: time.Hour, HTTPClient: hClient}
+	n.Start()
+	for i := 0; i < 5; i++ {
+		n.Add(Event{Op: OpDel, ID: "ID1"})
+	}
+	<-sending
+
+	start := time.Now()
+	n.Close()
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Close() took %v, want it to drop the events", d)
+	}
+	if actual := n.Metrics.sentDrop.Load(); actual != 5 {
+		t.Errorf("Incorrect number of sentDrop got: %v, want: %v\n", actual, 5)
+	}
+	n.Add(Event{Op: OpDel, ID: "ID1"}) // ignored after Close
+	if actual := n.Metrics.addSuc.Load(); actual != 5 {
+		t.Errorf("Incorrect number of addSuc got: %v, want: %v\n", actual, 5)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for condition")
+}
@@ -0,0 +1,196 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package ratelimit limits the request rate of each client on each keyspace with token buckets,
+// kept in memory or in Redis to share the limits across pods.
+package ratelimit
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Client identities to limit by
+const (
+	ByUA        = "ua"
+	ByPrincipal = "principal"
+	ByIP        = "ip"
+)
+
+const DefaultMaxBuckets = 100000
+
+// Limit is the rate of requests allowed for the matching client and keyspace
+type Limit struct {
+	Client   string  // client identity, "" matches any
+	Keyspace string  // "" matches any
+	Rate     float64 // requests per second
+	Burst    int     // bucket size, default Rate rounded up
+}
+
+// RateLimit holds the limits. Each client and keyspace pair has its own bucket, refilled at the
+// Rate of the first matching Limit. Pairs without a matching Limit are not limited.
+type RateLimit struct {
+	Enabled    bool
+	By         string // client identity, "ua" (default), "principal" or "ip"
+	Shared     bool   // keep the buckets in Redis, shared by all pods
+	Limits     []Limit
+	MaxBuckets int // max buckets kept in memory, least recently used dropped first, default 100000
+	Redis      redis.UniversalClient
+	mu         sync.Mutex
+	lru        *list.List // of *bucket, most recently used first
+	buckets    map[string]*list.Element
+	now        func() time.Time
+}
+
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+// Validate checks the config and sets defaults
+func (l *RateLimit) Validate() error {
+	switch l.By {
+	case "":
+		l.By = ByUA
+	case ByUA, ByPrincipal, ByIP:
+	default:
+		return fmt.Errorf("invalid By %q, must be %s, %s or %s", l.By, ByUA, ByPrincipal, ByIP)
+	}
+	for i := range l.Limits {
+		limit := &l.Limits[i]
+		if limit.Rate <= 0 {
+			return fmt.Errorf("limit %d: Rate must be greater than 0", i)
+		}
+		if limit.Burst < 1 {
+			limit.Burst = int(math.Ceil(limit.Rate))
+		}
+	}
+	if l.MaxBuckets < 1 {
+		l.MaxBuckets = DefaultMaxBuckets
+	}
+	l.lru = list.New()
+	l.buckets = make(map[string]*list.Element)
+	l.now = time.Now
+	return nil
+}
+
+// Allow takes a token from the bucket of client on keyspace. If there is none, it returns false
+// and how long until there will be one. Errors from Redis are returned with true, so requests are
+// not rejected when Redis is unavailable.
+func (l *RateLimit) Allow(ctx context.Context, client, keyspace string) (bool, time.Duration, error) {
+	if !l.Enabled {
+		return true, 0, nil
+	}
+	limit := l.match(client, keyspace)
+	if limit == nil {
+		return true, 0, nil
+	}
+	key := keyspace + ":" + client
+	if l.Shared {
+		if l.Redis == nil {
+			return true, 0, errors.New("shared rate limit requires Redis")
+		}
+		return l.allowRedis(ctx, key, limit)
+	}
+	ok, wait := l.allowLocal(key, limit)
+	return ok, wait, nil
+}
+
+// match returns the first Limit for client and keyspace, nil if there is none
+func (l *RateLimit) match(client, keyspace string) *Limit {
+	for i := range l.Limits {
+		limit := &l.Limits[i]
+		if (limit.Client == "" || limit.Client == client) && (limit.Keyspace == "" || limit.Keyspace == keyspace) {
+			return limit
+		}
+	}
+	return nil
+}
+
+func (l *RateLimit) allowLocal(key string, limit *Limit) (bool, time.Duration) {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var b *bucket
+	if elem, ok := l.buckets[key]; ok {
+		l.lru.MoveToFront(elem)
+		b = elem.Value.(*bucket)
+	} else {
+		if l.lru.Len() >= l.MaxBuckets {
+			l.dropOldest()
+		}
+		b = &bucket{key: key, tokens: float64(limit.Burst), last: now}
+		l.buckets[key] = l.lru.PushFront(b)
+	}
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+}
+
+// dropOldest removes the least recently used bucket. It is the most likely to have refilled, so
+// to be the same as a new one; if not, its client starts over with a full bucket.
+func (l *RateLimit) dropOldest() {
+	elem := l.lru.Back()
+	l.lru.Remove(elem)
+	delete(l.buckets, elem.Value.(*bucket).key)
+}
+
+// tokenBucket is the token bucket in a Redis hash of tokens and last update in ms. It returns
+// whether a token is taken and, if not, the ms until there will be one.
+var tokenBucket = redis.NewScript(`
+local rate, burst, now = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local b = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens, last = tonumber(b[1]) or burst, tonumber(b[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - last) * rate / 1000)
+local allowed, wait = 0, 0
+if tokens >= 1 then
+	tokens, allowed = tokens - 1, 1
+else
+	wait = math.ceil((1 - tokens) * 1000 / rate)
+end
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "last", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return {allowed, wait}
+`)
+
+func (l *RateLimit) allowRedis(ctx context.Context, key string, limit *Limit) (bool, time.Duration, error) {
+	// the hash tag keeps each bucket in one slot
+	result, err := tokenBucket.Run(ctx, l.Redis, []string{"ratelimit:{" + key + "}"},
+		limit.Rate, limit.Burst, l.now().UnixMilli()).Result()
+	if err != nil {
+		return true, 0, err
+	}
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 2 {
+		return true, 0, fmt.Errorf("unexpected rate limit result %v", result)
+	}
+	allowed, _ := values[0].(int64)
+	wait, _ := values[1].(int64)
+	return allowed == 1, time.Duration(wait) * time.Millisecond, nil
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRateLimit(t *testing.T, shared bool) (*RateLimit, *time.Time) {
+	l := &RateLimit{
+		Enabled: true,
+		Shared:  shared,
+		Limits: []Limit{
+			{Client: "vip", Rate: 100},
+			{Keyspace: "gs", Rate: 1, Burst: 2},
+		},
+	}
+	if shared {
+		mr := miniredis.RunT(t)
+		l.Redis = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	}
+	if err := l.Validate(); err != nil {
+		t.Fatal("Validate() returned error:", err)
+	}
+	now := time.Unix(1700000000, 0)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func testAllow(t *testing.T, shared bool) {
+	l, now := newTestRateLimit(t, shared)
+	allow := func(client, keyspace string, want bool, wantWait time.Duration) {
+		t.Helper()
+		ok, wait, err := l.Allow(context.Background(), client, keyspace)
+		if err != nil {
+			t.Fatal("Allow() returned error:", err)
+		}
+		if ok != want || wait != wantWait {
+			t.Errorf("Allow(%v, %v) got: %v, %v, want: %v, %v", client, keyspace, ok, wait, want, wantWait)
+		}
+	}
+
+	allow("a", "gs", true, 0)
+	allow("a", "gs", true, 0)
+	allow("a", "gs", false, time.Second)
+	allow("b", "gs", true, 0)    // own bucket
+	allow("a", "other", true, 0) // no limit
+	for i := 0; i < 10; i++ {
+		allow("vip", "gs", true, 0)
+	}
+
+	*now = now.Add(500 * time.Millisecond)
+	allow("a", "gs", false, 500*time.Millisecond)
+	*now = now.Add(500 * time.Millisecond)
+	allow("a", "gs", true, 0)
+	allow("a", "gs", false, time.Second)
+}
+
+func TestAllow(t *testing.T) {
+	testAllow(t, false)
+}
+
+func TestAllowShared(t *testing.T) {
+	testAllow(t, true)
+}
+
+func TestAllowDisabled(t *testing.T) {
+	l, _ := newTestRateLimit(t, false)
+	l.Enabled = false
+	for i := 0; i < 5; i++ {
+		if ok, _, _ := l.Allow(context.Background(), "a", "gs"); !ok {
+			t.Fatal("Allow() got false when disabled")
+		}
+	}
+}
+
+func TestMaxBuckets(t *testing.T) {
+	l, _ := newTestRateLimit(t, false)
+	l.MaxBuckets = 2
+	allow := func(client string) bool {
+		ok, _, _ := l.Allow(context.Background(), client, "gs")
+		return ok
+	}
+
+	// all the clients stay active, at the same time
+	allow("a")
+	allow("b")
+	allow("a")
+	for i := 0; i < 10; i++ {
+		allow(string(rune('c' + i)))
+		allow("a")
+		if n := len(l.buckets); n != 2 || l.lru.Len() != 2 {
+			t.Fatalf("buckets got: %v, %v, want: 2", n, l.lru.Len())
+		}
+	}
+	// "a" is used most recently each time, so it is kept with its tokens taken
+	if allow("a") {
+		t.Error("Allow(a) got true, want false")
+	}
+	if _, ok := l.buckets["gs:b"]; ok {
+		t.Error("least recently used bucket b is kept")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []*RateLimit{
+		{By: "cookie"},
+		{Limits: []Limit{{Rate: 0}}},
+	}
+	for _, l := range tests {
+		if err := l.Validate(); err == nil {
+			t.Errorf("Validate(%+v) got nil error, want error", l)
+		}
+	}
+	l := &RateLimit{Limits: []Limit{{Rate: 2.5}}}
+	if err := l.Validate(); err != nil || l.By != ByUA || l.Limits[0].Burst != 3 {
+		t.Errorf("Validate() defaults got: %v, %v, %v", err, l.By, l.Limits[0].Burst)
+	}
+}
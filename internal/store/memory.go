@@ -0,0 +1,270 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package store
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often expired keys are removed, they are never returned in the meantime
+const sweepInterval = time.Minute
+
+// Errors of the Memory store for operations on values of the wrong type
+var (
+	ErrNotInteger = errors.New("value is not an integer")
+	ErrNotHash    = errors.New("value is not a hash")
+)
+
+// Memory is the Store in memory, for tests and local development. Values are not shared across
+// instances and are lost on restart.
+type Memory struct {
+	mu    sync.Mutex
+	items map[string]*item
+	now   func() time.Time
+	done  chan struct{}
+}
+
+var _ Store = (*Memory)(nil)
+
+type item struct {
+	value   []byte
+	hash    map[string][]byte // for the hash ops, value is nil
+	expires time.Time         // zero if no expiry
+}
+
+// NewMemory returns an empty Store in memory, Close stops removing expired keys
+func NewMemory() *Memory {
+	m := &Memory{items: make(map[string]*item), now: time.Now, done: make(chan struct{})}
+	go m.sweep()
+	return m
+}
+
+func (m *Memory) sweep() {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+		}
+		m.mu.Lock()
+		now := m.now()
+		for key, it := range m.items {
+			if it.expired(now) {
+				delete(m.items, key)
+			}
+		}
+		m.mu.Unlock()
+	}
+}
+
+func (it *item) expired(now time.Time) bool {
+	return !it.expires.IsZero() && !now.Before(it.expires)
+}
+
+// get returns the item of key if it hasn't expired, m.mu must be held
+func (m *Memory) get(key string) *item {
+	it, ok := m.items[key]
+	if !ok {
+		return nil
+	}
+	if it.expired(m.now()) {
+		delete(m.items, key)
+		return nil
+	}
+	return it
+}
+
+// expires returns when an item set now with ttl expires
+func (m *Memory) expires(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return m.now().Add(ttl)
+}
+
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, error) {
+	value, _, err := m.GetTTL(ctx, key)
+	return value, err
+}
+
+func (m *Memory) GetTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	it := m.get(key)
+	if it == nil || it.hash != nil {
+		return nil, 0, ErrNotFound
+	}
+	var ttl time.Duration
+	if !it.expires.IsZero() {
+		ttl = it.expires.Sub(m.now())
+	}
+	return clone(it.value), ttl, nil
+}
+
+func (m *Memory) Set(ctx context.Context, key string, value []byte, ttl time.Duration, cond Condition) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	exists := m.get(key) != nil
+	if cond == IfNotExists && exists || cond == IfExists && !exists {
+		return false, nil
+	}
+	m.items[key] = &item{value: clone(value), expires: m.expires(ttl)}
+	return true, nil
+}
+
+func (m *Memory) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		if it := m.get(key); it != nil && it.hash == nil {
+			values[i] = clone(it.value)
+		}
+	}
+	return values, nil
+}
+
+func (m *Memory) Del(ctx context.Context, keys ...string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for _, key := range keys {
+		if m.get(key) != nil {
+			delete(m.items, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *Memory) Exists(ctx context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.get(key) != nil, nil
+}
+
+func (m *Memory) IncrBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	it := m.get(key)
+	if it == nil {
+		it = &item{value: []byte("0")}
+		m.items[key] = it
+	}
+	if it.hash != nil {
+		return 0, ErrNotInteger
+	}
+	v, err := strconv.ParseInt(string(it.value), 10, 64)
+	if err != nil {
+		return 0, ErrNotInteger
+	}
+	v += n
+	it.value = strconv.AppendInt(nil, v, 10)
+	if ttl > 0 {
+		it.expires = m.expires(ttl)
+	}
+	return v, nil
+}
+
+func (m *Memory) HGet(ctx context.Context, key, field string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	it := m.get(key)
+	if it == nil {
+		return nil, ErrNotFound
+	}
+	value, ok := it.hash[field]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clone(value), nil
+}
+
+func (m *Memory) HGetAll(ctx context.Context, key string) (map[string][]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	values := make(map[string][]byte)
+	if it := m.get(key); it != nil {
+		for field, value := range it.hash {
+			values[field] = clone(value)
+		}
+	}
+	return values, nil
+}
+
+func (m *Memory) HSet(ctx context.Context, key, field string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	it := m.get(key)
+	if it == nil {
+		it = &item{hash: make(map[string][]byte)}
+		m.items[key] = it
+	}
+	if it.hash == nil {
+		return ErrNotHash
+	}
+	it.hash[field] = clone(value)
+	if ttl > 0 {
+		it.expires = m.expires(ttl)
+	}
+	return nil
+}
+
+func (m *Memory) HDel(ctx context.Context, key string, fields ...string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	it := m.get(key)
+	if it == nil || it.hash == nil {
+		return 0, nil
+	}
+	var n int64
+	for _, field := range fields {
+		if _, ok := it.hash[field]; ok {
+			delete(it.hash, field)
+			n++
+		}
+	}
+	if len(it.hash) == 0 {
+		delete(m.items, key) // like Redis, empty hashes don't exist
+	}
+	return n, nil
+}
+
+// Close stops removing expired keys, the values are kept
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	select {
+	case <-m.done:
+	default:
+		close(m.done)
+	}
+	return nil
+}
+
+// clone returns a copy of b, so callers can't change stored values, nil stays nil
+func clone(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is the Store in Redis, the keys of Redis Cluster must have a hash tag to use MGet and Del
+// with several keys, see redisKey
+type Redis struct {
+	rdb redis.UniversalClient
+}
+
+var _ Store = (*Redis)(nil)
+
+// NewRedis returns the Store in rdb, it is closed with the Store
+func NewRedis(rdb redis.UniversalClient) *Redis {
+	return &Redis{rdb: rdb}
+}
+
+func (s *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := s.rdb.Get(ctx, key).Bytes()
+	return value, redisErr(err)
+}
+
+func (s *Redis) GetTTL(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	pipe := s.rdb.Pipeline()
+	get := pipe.Get(ctx, key)
+	ttl := pipe.TTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, 0, redisErr(err)
+	}
+	value, _ := get.Bytes()
+	return value, max(ttl.Val(), 0), nil // negative if there is no expiry
+}
+
+func (s *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration, cond Condition) (bool, error) {
+	switch cond {
+	case IfNotExists:
+		return s.rdb.SetNX(ctx, key, value, ttl).Result()
+	case IfExists:
+		return s.rdb.SetXX(ctx, key, value, ttl).Result()
+	default:
+		err := s.rdb.Set(ctx, key, value, ttl).Err()
+		return err == nil, err
+	}
+}
+
+func (s *Redis) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	results, err := s.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	values := make([][]byte, len(results))
+	for i, result := range results {
+		if value, ok := result.(string); ok {
+			values[i] = []byte(value)
+		}
+	}
+	return values, nil
+}
+
+func (s *Redis) Del(ctx context.Context, keys ...string) (int64, error) {
+	return s.rdb.Del(ctx, keys...).Result()
+}
+
+func (s *Redis) Exists(ctx context.Context, key string) (bool, error) {
+	n, err := s.rdb.Exists(ctx, key).Result()
+	return n > 0, err
+}
+
+func (s *Redis) IncrBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, error) {
+	pipe := s.rdb.Pipeline()
+	result := pipe.IncrBy(ctx, key, n)
+	if ttl > 0 {
+		pipe.Expire(ctx, key, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return result.Val(), err
+}
+
+func (s *Redis) HGet(ctx context.Context, key, field string) ([]byte, error) {
+	value, err := s.rdb.HGet(ctx, key, field).Bytes()
+	return value, redisErr(err)
+}
+
+func (s *Redis) HGetAll(ctx context.Context, key string) (map[string][]byte, error) {
+	fields, err := s.rdb.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string][]byte, len(fields))
+	for field, value := range fields {
+		values[field] = []byte(value)
+	}
+	return values, nil
+}
+
+func (s *Redis) HSet(ctx context.Context, key, field string, value []byte, ttl time.Duration) error {
+	pipe := s.rdb.Pipeline()
+	pipe.HSet(ctx, key, field, value)
+	if ttl > 0 {
+		pipe.Expire(ctx, key, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s *Redis) HDel(ctx context.Context, key string, fields ...string) (int64, error) {
+	return s.rdb.HDel(ctx, key, fields...).Result()
+}
+
+func (s *Redis) Close() error {
+	return s.rdb.Close()
+}
+
+// redisErr returns ErrNotFound for a nil reply
+func redisErr(err error) error {
+	if err == redis.Nil {
+		return ErrNotFound
+	}
+	return err
+}
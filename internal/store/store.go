@@ -0,0 +1,71 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package store is the storage backend of the keyspaces, in Redis or in memory for tests and
+// local development.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Backends
+const (
+	BackendRedis  = "redis"
+	BackendMemory = "memory"
+)
+
+// ErrNotFound is returned when the key or field doesn't exist
+var ErrNotFound = errors.New("key not found")
+
+// Condition is the condition for Set to write the value
+type Condition int
+
+const (
+	Always      Condition = iota
+	IfNotExists           // only set the key if it doesn't exist, like SET NX
+	IfExists              // only set the key if it exists, like SET XX
+)
+
+// Store holds values by key with an expiry. A ttl of 0 means no expiry, or for IncrBy and HSet
+// that the expiry is not changed. The Redis store returns the error of ctx once it is done.
+type Store interface {
+	// Get returns the value of key, ErrNotFound if it doesn't exist
+	Get(ctx context.Context, key string) ([]byte, error)
+	// GetTTL returns the value of key and its remaining time to live, 0 if it has no expiry
+	GetTTL(ctx context.Context, key string) ([]byte, time.Duration, error)
+	// Set sets the value of key if cond is met, and returns whether it was set
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration, cond Condition) (bool, error)
+	// MGet returns the values of keys, nil for keys that don't exist
+	MGet(ctx context.Context, keys ...string) ([][]byte, error)
+	// Del deletes keys and returns the number of keys that existed
+	Del(ctx context.Context, keys ...string) (int64, error)
+	// Exists returns whether key exists
+	Exists(ctx context.Context, key string) (bool, error)
+	// IncrBy increments the integer value of key by n, from 0 if it doesn't exist, and returns it
+	IncrBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, error)
+	// HGet returns the value of field in the hash of key, ErrNotFound if either doesn't exist
+	HGet(ctx context.Context, key, field string) ([]byte, error)
+	// HGetAll returns all the fields of the hash of key, empty if it doesn't exist
+	HGetAll(ctx context.Context, key string) (map[string][]byte, error)
+	// HSet sets field in the hash of key
+	HSet(ctx context.Context, key, field string, value []byte, ttl time.Duration) error
+	// HDel deletes fields from the hash of key and returns the number of fields that existed
+	HDel(ctx context.Context, key string, fields ...string) (int64, error)
+	// Close releases the resources of the store
+	Close() error
+}
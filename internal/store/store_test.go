@@ -0,0 +1,114 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package store
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// testStore runs the same tests on each Store, advance moves the clock forward
+func testStore(t *testing.T, s Store, advance func(time.Duration)) {
+	ctx := context.Background()
+
+	if _, err := s.Get(ctx, "a"); err != ErrNotFound {
+		t.Errorf("Get() missing err got: %v, want: %v", err, ErrNotFound)
+	}
+	if ok, err := s.Set(ctx, "a", []byte("1"), time.Minute, Always); !ok || err != nil {
+		t.Errorf("Set() got: %v %v, want: true nil", ok, err)
+	}
+	if ok, _ := s.Set(ctx, "a", []byte("2"), time.Minute, IfNotExists); ok {
+		t.Error("Set() IfNotExists on existing key got: true")
+	}
+	if ok, _ := s.Set(ctx, "b", []byte("2"), 0, IfExists); ok {
+		t.Error("Set() IfExists on missing key got: true")
+	}
+	if ok, _ := s.Set(ctx, "b", []byte("2"), 0, IfNotExists); !ok {
+		t.Error("Set() IfNotExists on missing key got: false")
+	}
+	if v, err := s.Get(ctx, "a"); err != nil || !bytes.Equal(v, []byte("1")) {
+		t.Errorf("Get() got: %q %v, want: 1", v, err)
+	}
+	if _, ttl, err := s.GetTTL(ctx, "a"); err != nil || ttl <= 0 || ttl > time.Minute {
+		t.Errorf("GetTTL() got: %v %v, want: 1m", ttl, err)
+	}
+	if _, ttl, err := s.GetTTL(ctx, "b"); err != nil || ttl != 0 {
+		t.Errorf("GetTTL() without expiry got: %v %v, want: 0", ttl, err)
+	}
+	values, err := s.MGet(ctx, "a", "missing", "b")
+	if err != nil || len(values) != 3 || string(values[0]) != "1" || values[1] != nil || string(values[2]) != "2" {
+		t.Errorf("MGet() got: %q %v", values, err)
+	}
+	if ok, _ := s.Exists(ctx, "b"); !ok {
+		t.Error("Exists() got: false")
+	}
+	if n, err := s.Del(ctx, "b", "missing"); n != 1 || err != nil {
+		t.Errorf("Del() got: %d %v, want: 1", n, err)
+	}
+	if ok, _ := s.Exists(ctx, "b"); ok {
+		t.Error("Exists() after Del got: true")
+	}
+
+	for _, incr := range []struct{ n, want int64 }{{5, 5}, {-2, 3}} {
+		if n, err := s.IncrBy(ctx, "n", incr.n, time.Minute); n != incr.want || err != nil {
+			t.Errorf("IncrBy() got: %d %v, want: %d", n, err, incr.want)
+		}
+	}
+
+	if err := s.HSet(ctx, "h", "f1", []byte("v1"), time.Minute); err != nil {
+		t.Error("HSet() returned error:", err)
+	}
+	s.HSet(ctx, "h", "f2", []byte("v2"), 0)
+	if v, err := s.HGet(ctx, "h", "f1"); err != nil || string(v) != "v1" {
+		t.Errorf("HGet() got: %q %v, want: v1", v, err)
+	}
+	if _, err := s.HGet(ctx, "h", "missing"); err != ErrNotFound {
+		t.Errorf("HGet() missing err got: %v, want: %v", err, ErrNotFound)
+	}
+	if all, err := s.HGetAll(ctx, "h"); err != nil || len(all) != 2 || string(all["f2"]) != "v2" {
+		t.Errorf("HGetAll() got: %q %v", all, err)
+	}
+	if n, _ := s.HDel(ctx, "h", "f1", "missing"); n != 1 {
+		t.Errorf("HDel() got: %d, want: 1", n)
+	}
+
+	advance(2 * time.Minute)
+	for _, key := range []string{"a", "n", "h"} {
+		if ok, _ := s.Exists(ctx, key); ok {
+			t.Errorf("%s has not expired", key)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Error("Close() returned error:", err)
+	}
+}
+
+func TestRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	testStore(t, NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()})), mr.FastForward)
+}
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	testStore(t, m, func(d time.Duration) { now = now.Add(d) })
+}
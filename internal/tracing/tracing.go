@@ -0,0 +1,131 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package tracing sets up OpenTelemetry tracing, exported with OTLP over HTTP or to a file.
+// When it is not enabled, the global TracerProvider is a no-op and spans cost little.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters
+const (
+	ExporterOTLP = "otlp"
+	ExporterFile = "file"
+)
+
+// Tracing holds the config of the tracer provider
+type Tracing struct {
+	Enabled     bool
+	Exporter    string            // ExporterOTLP (default) or ExporterFile
+	Endpoint    string            // OTLP HTTP URL, default from OTEL_EXPORTER_OTLP_ENDPOINT or https://localhost:4318/v1/traces
+	Headers     map[string]string // OTLP HTTP headers, e.g. for authentication
+	File        string            // file to write spans to as JSON, one per line, for ExporterFile
+	SampleRatio float64           // ratio of traces sampled, unless the parent is sampled, default 1
+	provider    *sdktrace.TracerProvider
+	file        *os.File
+}
+
+// Tracer returns the tracer of an instrumentation scope, e.g. a package
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer(name)
+}
+
+// Validate checks the config and sets defaults
+func (t *Tracing) Validate() error {
+	if !t.Enabled {
+		return nil
+	}
+	switch t.Exporter {
+	case "":
+		t.Exporter = ExporterOTLP
+	case ExporterOTLP:
+	case ExporterFile:
+		if t.File == "" {
+			return errors.New("file exporter requires File")
+		}
+	default:
+		return fmt.Errorf("invalid Exporter %q, must be %s or %s", t.Exporter, ExporterOTLP, ExporterFile)
+	}
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		return errors.New("SampleRatio must be between 0 and 1")
+	}
+	if t.SampleRatio == 0 {
+		t.SampleRatio = 1
+	}
+	return nil
+}
+
+// Start sets the global TracerProvider and W3C trace context propagator, if enabled
+func (t *Tracing) Start(service, version string) error {
+	if !t.Enabled {
+		return nil
+	}
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch t.Exporter {
+	case ExporterFile:
+		if t.file, err = os.OpenFile(t.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644); err != nil {
+			return err
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(t.file))
+	default:
+		opts := []otlptracehttp.Option{otlptracehttp.WithHeaders(t.Headers)}
+		if t.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(t.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(context.Background(), opts...)
+	}
+	if err != nil {
+		return err
+	}
+
+	t.provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceName(service), semconv.ServiceVersion(version))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(t.SampleRatio))),
+	)
+	otel.SetTracerProvider(t.provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{},
+		propagation.Baggage{}))
+	return nil
+}
+
+// Shutdown exports the remaining spans and stops the tracer provider
+func (t *Tracing) Shutdown(ctx context.Context) error {
+	if t.provider == nil {
+		return nil
+	}
+	err := t.provider.Shutdown(ctx)
+	if t.file != nil {
+		err = errors.Join(err, t.file.Close())
+	}
+	return err
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright 2025 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tracing Tracing
+		wantErr bool
+	}{
+		{"disabled", Tracing{Exporter: "bad"}, false},
+		{"defaults", Tracing{Enabled: true}, false},
+		{"file without File", Tracing{Enabled: true, Exporter: ExporterFile}, true},
+		{"unknown exporter", Tracing{Enabled: true, Exporter: "bad"}, true},
+		{"bad SampleRatio", Tracing{Enabled: true, SampleRatio: 2}, true},
+	}
+	for _, tt := range tests {
+		err := tt.tracing.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+	tr := Tracing{Enabled: true}
+	tr.Validate()
+	if tr.Exporter != ExporterOTLP || tr.SampleRatio != 1 {
+		t.Errorf("defaults got: %q %v, want: %q 1", tr.Exporter, tr.SampleRatio, ExporterOTLP)
+	}
+}
+
+func TestRedisHookFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "spans.json")
+	tr := Tracing{Enabled: true, Exporter: ExporterFile, File: file}
+	if err := tr.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Start("xdas", "test"); err != nil {
+		t.Fatal("Start() returned error:", err)
+	}
+
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	rdb.AddHook(RedisHook{})
+	ctx, span := Tracer("test").Start(context.Background(), "request")
+	rdb.Set(ctx, "k", "v", 0)
+	rdb.Get(ctx, "missing") // redis.Nil is not an error
+	pipe := rdb.Pipeline()
+	pipe.Get(ctx, "k")
+	pipe.Exec(ctx)
+	span.End()
+	if err := tr.Shutdown(context.Background()); err != nil {
+		t.Fatal("Shutdown() returned error:", err)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	type exported struct {
+		Name   string
+		Parent struct{ SpanID string }
+		Status struct{ Code string }
+	}
+	var request string
+	spans := map[string]exported{}
+	for dec := json.NewDecoder(f); dec.More(); {
+		var s struct {
+			exported
+			SpanContext struct{ SpanID string }
+		}
+		if err := dec.Decode(&s); err != nil {
+			t.Fatal(err)
+		}
+		if s.Name == "request" {
+			request = s.SpanContext.SpanID
+		}
+		spans[s.Name] = s.exported
+	}
+	if request == "" {
+		t.Fatal("no request span exported")
+	}
+	for _, name := range []string{"SET", "GET", "PIPELINE"} {
+		s, ok := spans[name]
+		if !ok {
+			t.Errorf("no %s span in %v", name, spans)
+			continue
+		}
+		if s.Parent.SpanID != request {
+			t.Errorf("%s parent got: %s, want: %s", name, s.Parent.SpanID, request)
+		}
+		if s.Status.Code == "Error" {
+			t.Errorf("%s status got: Error", name)
+		}
+	}
+}